@@ -0,0 +1,224 @@
+package browserclient
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Caracteres, versões e ordens usados pelo Chromium para gerar a marca GREASE
+// (components/embedder_support/user_agent_utils.cc)
+var (
+	greaseBrandChars = []string{" ", "(", ":", "-", ".", "/", ")", ";", "=", "?", "_"}
+	greaseVersions   = []string{"8", "99", "24"}
+	greaseOrders     = [6][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+)
+
+// Versões completas publicadas do Chrome estável por major; o UA reduzido só
+// traz "NNN.0.0.0", mas Sec-Ch-Ua-Full-Version-List precisa do build real
+var chromeFullVersions = map[string]string{
+	"120": "120.0.6099.225",
+	"121": "121.0.6167.185",
+	"122": "122.0.6261.129",
+	"123": "123.0.6312.122",
+	"124": "124.0.6367.207",
+	"125": "125.0.6422.142",
+	"126": "126.0.6478.127",
+	"127": "127.0.6533.120",
+	"128": "128.0.6613.138",
+	"129": "129.0.6668.101",
+	"130": "130.0.6723.117",
+	"131": "131.0.6778.205",
+}
+
+// Builds do Edge estável por major (numeração própria da Microsoft)
+var edgeFullVersions = map[string]string{
+	"120": "120.0.2210.144",
+	"121": "121.0.2277.128",
+	"122": "122.0.2365.92",
+	"123": "123.0.2420.97",
+	"124": "124.0.2478.109",
+	"125": "125.0.2535.92",
+	"126": "126.0.2592.113",
+	"127": "127.0.2651.105",
+	"128": "128.0.2739.79",
+	"129": "129.0.2792.89",
+	"130": "130.0.2849.80",
+	"131": "131.0.2903.112",
+}
+
+// Builds do Opera estável por major (o Opera N usa o Chromium N+14)
+var operaFullVersions = map[string]string{
+	"106": "106.0.4998.70",
+	"107": "107.0.5045.36",
+	"108": "108.0.5067.40",
+	"109": "109.0.5097.80",
+	"110": "110.0.5130.66",
+	"111": "111.0.5168.61",
+	"112": "112.0.5197.53",
+	"113": "113.0.5230.62",
+	"114": "114.0.5282.102",
+	"115": "115.0.5322.77",
+	"116": "116.0.5366.35",
+}
+
+// UABrand é uma entrada de Sec-Ch-Ua
+type UABrand struct {
+	Brand   string
+	Version string
+}
+
+// GreasedBrands reproduz o algoritmo do Chromium: a marca GREASE, sua versão
+// e a posição de cada marca derivam do major do Chromium (seed). brand vazio
+// gera a lista de um Chromium puro, só com GREASE e "Chromium".
+func GreasedBrands(seed int, chromium UABrand, brand UABrand, full bool) []UABrand {
+	grease := UABrand{
+		Brand:   "Not" + greaseBrandChars[seed%len(greaseBrandChars)] + "A" + greaseBrandChars[(seed+1)%len(greaseBrandChars)] + "Brand",
+		Version: greaseVersions[seed%len(greaseVersions)],
+	}
+	if full {
+		grease.Version += ".0.0.0"
+	}
+
+	if brand.Brand == "" {
+		// Com duas marcas o Chromium só alterna pela paridade do seed
+		list := make([]UABrand, 2)
+		list[seed%2] = grease
+		list[(seed+1)%2] = chromium
+		return list
+	}
+
+	order := greaseOrders[seed%len(greaseOrders)]
+	list := make([]UABrand, 3)
+	list[order[0]] = grease
+	list[order[1]] = chromium
+	list[order[2]] = brand
+	return list
+}
+
+// FormatBrands serializa a lista no formato de Sec-Ch-Ua
+func FormatBrands(brands []UABrand) string {
+	parts := make([]string, len(brands))
+	for i, b := range brands {
+		parts[i] = strconv.Quote(b.Brand) + ";v=" + strconv.Quote(b.Version)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BrandList é o Sec-Ch-Ua (full: Sec-Ch-Ua-Full-Version-List) do navegador
+// Chromium descrito pelo User-Agent
+func BrandList(userAgent string, full bool) string {
+	return brandList(detectBrowser(userAgent), userAgent, full)
+}
+
+// profileBrandList considera a família declarada no perfil (ex.: Brave)
+func profileBrandList(profile *BrowserProfile, full bool) string {
+	return brandList(profileBrowser(profile), profile.UserAgent, full)
+}
+
+func brandList(browser, userAgent string, full bool) string {
+	major := chromeMajorVersion(userAgent)
+	seed, _ := strconv.Atoi(major)
+
+	chromium := UABrand{Brand: "Chromium", Version: major}
+	if full {
+		chromium.Version = chromeFullVersion(major)
+		if browser == "Brave" {
+			chromium.Version = major + ".0.0.0"
+		}
+	}
+	brand := UABrand{Brand: brandName(browser)}
+	brand.Version = brandVersion(browser, userAgent, full)
+	return FormatBrands(GreasedBrands(seed, chromium, brand, full))
+}
+
+// brandName é a marca própria do navegador em Sec-Ch-Ua
+func brandName(browser string) string {
+	switch browser {
+	case "Edge":
+		return "Microsoft Edge"
+	case "Opera":
+		return "Opera"
+	case "Brave":
+		return "Brave"
+	case "Samsung":
+		return "Samsung Internet"
+	}
+	return "Google Chrome"
+}
+
+// brandVersion é a versão da marca própria: Edge e Opera têm numeração
+// própria; o Brave acompanha o Chromium mas reduz a versão completa
+func brandVersion(browser, userAgent string, full bool) string {
+	major := chromeMajorVersion(userAgent)
+	switch browser {
+	case "Edge":
+		if edge := uaVersion(userAgent, "Edg/"); edge != "" {
+			major = majorOf(edge)
+		}
+		if full {
+			return lookupFullVersion(edgeFullVersions, major)
+		}
+	case "Opera":
+		if opera := uaVersion(userAgent, "OPR/"); opera != "" {
+			major = majorOf(opera)
+		}
+		if full {
+			return lookupFullVersion(operaFullVersions, major)
+		}
+	case "Brave":
+		if full {
+			return major + ".0.0.0"
+		}
+	case "Samsung":
+		// A marca usa a versão do UA ("25.0"); sem tabela de builds
+		version := uaVersion(userAgent, "SamsungBrowser/")
+		if version == "" {
+			return major
+		}
+		if full {
+			return majorOf(version) + ".0.0.0"
+		}
+		return version
+	default:
+		if full {
+			return chromeFullVersion(major)
+		}
+	}
+	return major
+}
+
+func chromeMajorVersion(userAgent string) string {
+	if version := uaVersion(userAgent, "Chrome/"); version != "" {
+		return majorOf(version)
+	}
+	return "126"
+}
+
+func chromeFullVersion(major string) string {
+	return lookupFullVersion(chromeFullVersions, major)
+}
+
+func lookupFullVersion(versions map[string]string, major string) string {
+	if full, ok := versions[major]; ok {
+		return full
+	}
+	return major + ".0.0.0"
+}
+
+// uaVersion retorna a versão que segue token no User-Agent (ex.: "Chrome/")
+func uaVersion(userAgent, token string) string {
+	i := strings.Index(userAgent, token)
+	if i < 0 {
+		return ""
+	}
+	version := userAgent[i+len(token):]
+	if end := strings.IndexAny(version, " ;)"); end >= 0 {
+		version = version[:end]
+	}
+	return version
+}
+
+func majorOf(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
@@ -0,0 +1,55 @@
+package browserclient
+
+import (
+	"strconv"
+	"testing"
+)
+
+// Valores de Sec-Ch-Ua capturados dos navegadores
+func TestBrandList(t *testing.T) {
+	const win = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) "
+	tests := []struct {
+		name string
+		ua   string
+		want string
+	}{
+		{"chrome 120", win + "Chrome/120.0.0.0 Safari/537.36", `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+		{"chrome 124", win + "Chrome/124.0.0.0 Safari/537.36", `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`},
+		{"chrome 125", win + "Chrome/125.0.0.0 Safari/537.36", `"Google Chrome";v="125", "Chromium";v="125", "Not.A/Brand";v="24"`},
+		{"chrome 126", win + "Chrome/126.0.0.0 Safari/537.36", `"Not/A)Brand";v="8", "Chromium";v="126", "Google Chrome";v="126"`},
+		{"chrome 131", win + "Chrome/131.0.0.0 Safari/537.36", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`},
+		{"edge 126", win + "Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", `"Not/A)Brand";v="8", "Chromium";v="126", "Microsoft Edge";v="126"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BrandList(tt.ua, false); got != tt.want {
+				t.Errorf("BrandList() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGreasedBrandsTwoBrands(t *testing.T) {
+	tests := []struct {
+		seed int
+		want string
+	}{
+		{125, `"Chromium";v="125", "Not.A/Brand";v="24"`},
+		{126, `"Not/A)Brand";v="8", "Chromium";v="126"`},
+	}
+	for _, tt := range tests {
+		chromium := UABrand{Brand: "Chromium", Version: strconv.Itoa(tt.seed)}
+		if got := FormatBrands(GreasedBrands(tt.seed, chromium, UABrand{}, false)); got != tt.want {
+			t.Errorf("seed %d: %s, want %s", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestGreasedBrandsFullVersion(t *testing.T) {
+	brands := GreasedBrands(126, UABrand{Brand: "Chromium", Version: "126.0.6478.127"}, UABrand{Brand: "Google Chrome", Version: "126.0.6478.127"}, true)
+	for _, b := range brands {
+		if b.Brand == "Not/A)Brand" && b.Version != "8.0.0.0" {
+			t.Errorf("GREASE full version = %q, want 8.0.0.0", b.Version)
+		}
+	}
+}
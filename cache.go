@@ -0,0 +1,807 @@
+package browserclient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMemoryCacheSize = 64 << 20
+	defaultDiskCacheSize   = 512 << 20
+	defaultMaxEntrySize    = 10 << 20
+
+	// CacheHeader marca respostas servidas pelo cache ("1")
+	CacheHeader = "X-From-Cache"
+)
+
+// Status que podem ter frescor heurístico (RFC 9110, seção 15.1)
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// Status armazenáveis com frescor explícito
+var cacheableStatus = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 302: true, 307: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// Headers que não são armazenados (RFC 9111, seção 3.1) e Set-Cookie, que o
+// navegador não reaplica a partir do cache
+var unstoredHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Proxy-Authenticate",
+	"Proxy-Authorization", "TE", "Trailer", "Transfer-Encoding", "Upgrade", "Set-Cookie",
+}
+
+// Headers que um 304 não atualiza na resposta armazenada
+var notUpdatedHeaders = map[string]bool{
+	"Content-Length": true, "Content-Encoding": true, "Transfer-Encoding": true, "Content-Range": true,
+}
+
+// CacheStorage é o backend do cache HTTP
+type CacheStorage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// HTTPCache é um cache privado (RFC 9111) como o de um navegador
+type HTTPCache struct {
+	Storage CacheStorage
+	// Maior corpo armazenado (padrão: 10 MB)
+	MaxEntrySize int64
+	// Desativa o frescor heurístico baseado em Last-Modified
+	DisableHeuristic bool
+}
+
+// NewHTTPCache cria um cache com o backend informado (nil usa memória)
+func NewHTTPCache(storage CacheStorage) *HTTPCache {
+	if storage == nil {
+		storage = NewMemoryCache(0)
+	}
+	return &HTTPCache{Storage: storage}
+}
+
+// cachedVariant é uma resposta armazenada para um conjunto de headers Vary
+type cachedVariant struct {
+	Vary         map[string]string `json:"vary,omitempty"`
+	StatusCode   int               `json:"status_code"`
+	Status       string            `json:"status"`
+	Proto        string            `json:"proto"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+}
+
+type cacheEntry struct {
+	Variants []*cachedVariant `json:"variants"`
+}
+
+func cacheKey(u *url.URL) string {
+	clean := *u
+	clean.Fragment = ""
+	return clean.String()
+}
+
+func (c *HTTPCache) load(key string) *cacheEntry {
+	data, ok := c.Storage.Get(key)
+	if !ok {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.Storage.Delete(key)
+		return nil
+	}
+	return &entry
+}
+
+func (c *HTTPCache) save(key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.Storage.Set(key, data)
+}
+
+// lookup encontra a variante compatível com os headers da requisição
+func (c *HTTPCache) lookup(req *http.Request) *cachedVariant {
+	entry := c.load(cacheKey(req.URL))
+	if entry == nil {
+		return nil
+	}
+	for _, v := range entry.Variants {
+		if v.matches(req) {
+			return v
+		}
+	}
+	return nil
+}
+
+// store grava a variante, substituindo a de mesmo Vary
+func (c *HTTPCache) store(req *http.Request, variant *cachedVariant) {
+	key := cacheKey(req.URL)
+	entry := c.load(key)
+	if entry == nil {
+		entry = &cacheEntry{}
+	}
+
+	variants := entry.Variants[:0]
+	for _, v := range entry.Variants {
+		if !v.sameVary(variant) {
+			variants = append(variants, v)
+		}
+	}
+	entry.Variants = append(variants, variant)
+	c.save(key, entry)
+}
+
+// kind identifica o backend no HAR, como o _fromCache do DevTools
+func (c *HTTPCache) kind() string {
+	if _, ok := c.Storage.(*DiskCache); ok {
+		return "disk"
+	}
+	return "memory"
+}
+
+// Invalidate remove as respostas armazenadas de uma URL
+func (c *HTTPCache) Invalidate(u *url.URL) {
+	c.Storage.Delete(cacheKey(u))
+}
+
+func (v *cachedVariant) matches(req *http.Request) bool {
+	for name, value := range v.Vary {
+		if normalizeVaryValue(req.Header.Values(name)) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *cachedVariant) sameVary(other *cachedVariant) bool {
+	if len(v.Vary) != len(other.Vary) {
+		return false
+	}
+	for name, value := range v.Vary {
+		if other.Vary[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeVaryValue(values []string) string {
+	return strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+}
+
+// response reconstrói a resposta armazenada
+func (v *cachedVariant) response(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        v.Status,
+		StatusCode:    v.StatusCode,
+		Proto:         v.Proto,
+		Header:        v.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(v.Body)),
+		ContentLength: int64(len(v.Body)),
+		Request:       req,
+	}
+	resp.ProtoMajor, resp.ProtoMinor, _ = http.ParseHTTPVersion(v.Proto)
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	}
+	return resp
+}
+
+// currentAge segue a RFC 9111, seção 4.2.3
+func (v *cachedVariant) currentAge(now time.Time) time.Duration {
+	date := parseHTTPDate(v.Header.Get("Date"), v.ResponseTime)
+
+	apparentAge := v.ResponseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	ageValue := time.Duration(0)
+	if age, err := strconv.Atoi(v.Header.Get("Age")); err == nil && age > 0 {
+		ageValue = time.Duration(age) * time.Second
+	}
+	correctedAge := ageValue + v.ResponseTime.Sub(v.RequestTime)
+
+	initialAge := apparentAge
+	if correctedAge > initialAge {
+		initialAge = correctedAge
+	}
+	return initialAge + now.Sub(v.ResponseTime)
+}
+
+// freshnessLifetime segue a RFC 9111, seção 4.2.1 (cache privado: ignora s-maxage)
+func (c *HTTPCache) freshnessLifetime(v *cachedVariant) time.Duration {
+	cc := parseCacheControl(v.Header)
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	date := parseHTTPDate(v.Header.Get("Date"), v.ResponseTime)
+	if expires := v.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			// Expires inválido significa já expirado
+			return 0
+		}
+		return t.Sub(date)
+	}
+
+	// Heurística dos navegadores: 10% do tempo desde a última modificação
+	if !c.DisableHeuristic && heuristicallyCacheable[v.StatusCode] {
+		if lm, err := http.ParseTime(v.Header.Get("Last-Modified")); err == nil && date.After(lm) {
+			return date.Sub(lm) / 10
+		}
+	}
+	return 0
+}
+
+// isFresh decide se a variante pode ser servida sem ir ao servidor
+func (c *HTTPCache) isFresh(v *cachedVariant, reqCC cacheControl, now time.Time) bool {
+	respCC := parseCacheControl(v.Header)
+	if respCC.has("no-cache") || reqCC.has("no-cache") {
+		return false
+	}
+
+	lifetime := c.freshnessLifetime(v)
+	age := v.currentAge(now)
+
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+	if age < lifetime {
+		return true
+	}
+
+	// max-stale só vale se o servidor não exigiu revalidação
+	if respCC.has("must-revalidate") {
+		return false
+	}
+	if reqCC.has("max-stale") {
+		maxStale, ok := reqCC.seconds("max-stale")
+		return !ok || age-lifetime < maxStale
+	}
+	return false
+}
+
+// cacheable decide se a resposta pode ser armazenada (RFC 9111, seção 3)
+func (c *HTTPCache) cacheable(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodGet || !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	if parseCacheControl(req.Header).has("no-store") {
+		return false
+	}
+
+	respCC := parseCacheControl(resp.Header)
+	if respCC.has("no-store") {
+		return false
+	}
+	if strings.Contains(resp.Header.Get("Vary"), "*") {
+		return false
+	}
+
+	maxEntry := c.MaxEntrySize
+	if maxEntry <= 0 {
+		maxEntry = defaultMaxEntrySize
+	}
+	if resp.ContentLength > maxEntry {
+		return false
+	}
+
+	// Sem frescor explícito, só status com heurística ou com validador
+	explicit := respCC.has("max-age") || resp.Header.Get("Expires") != ""
+	if !explicit && !heuristicallyCacheable[resp.StatusCode] {
+		return false
+	}
+	return explicit || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" || respCC.has("no-cache")
+}
+
+func newCachedVariant(req *http.Request, resp *http.Response, body []byte, requestTime, responseTime time.Time) *cachedVariant {
+	header := resp.Header.Clone()
+	for _, h := range unstoredHeaders {
+		header.Del(h)
+	}
+	for _, h := range resp.Header.Values("Connection") {
+		for _, name := range strings.Split(h, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	header.Del(CacheHeader)
+
+	v := &cachedVariant{
+		StatusCode:   resp.StatusCode,
+		Status:       resp.Status,
+		Proto:        resp.Proto,
+		Header:       header,
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+
+	for _, vary := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if v.Vary == nil {
+				v.Vary = make(map[string]string)
+			}
+			v.Vary[name] = normalizeVaryValue(req.Header.Values(name))
+		}
+	}
+	return v
+}
+
+// update aplica os headers de um 304 à variante (RFC 9111, seção 4.3.4)
+func (v *cachedVariant) update(resp *http.Response, requestTime, responseTime time.Time) {
+	for name, values := range resp.Header {
+		if notUpdatedHeaders[name] || name == CacheHeader {
+			continue
+		}
+		skip := false
+		for _, h := range unstoredHeaders {
+			if h == name {
+				skip = true
+			}
+		}
+		if !skip {
+			v.Header[name] = values
+		}
+	}
+	v.RequestTime = requestTime
+	v.ResponseTime = responseTime
+}
+
+// cacheTransport aplica o HTTPCache a cada ida ao servidor
+type cacheTransport struct {
+	next  http.RoundTripper
+	cache *HTTPCache
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < 400 && !isSafeMethod(req.Method) {
+			t.invalidate(req, resp)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	// Hard reload (Pragma/Cache-Control no-cache) ignora o cache como os
+	// navegadores; a resposta nova ainda é armazenada
+	hardReload := reqCC.has("no-cache") || (len(reqCC) == 0 && strings.Contains(req.Header.Get("Pragma"), "no-cache"))
+	userConditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+
+	var variant *cachedVariant
+	if !reqCC.has("no-store") && !hardReload && !userConditional {
+		variant = t.cache.lookup(req)
+	}
+
+	now := time.Now()
+	if variant != nil && t.cache.isFresh(variant, reqCC, now) {
+		resp := variant.response(req)
+		resp.Header.Set("Age", strconv.Itoa(int(variant.currentAge(now).Seconds())))
+		resp.Header.Set(CacheHeader, "1")
+		return resp, nil
+	}
+
+	if reqCC.has("only-if-cached") {
+		return &http.Response{
+			Status:     "504 Gateway Timeout",
+			StatusCode: http.StatusGatewayTimeout,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	outReq := req
+	if variant != nil {
+		// Revalidação condicional com os validadores armazenados
+		etag, lastModified := variant.Header.Get("ETag"), variant.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	resp, err := t.next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := time.Now()
+
+	if variant != nil && resp.StatusCode == http.StatusNotModified && outReq != req {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		variant.update(resp, requestTime, responseTime)
+		t.cache.store(req, variant)
+
+		cached := variant.response(req)
+		cached.Header.Set(CacheHeader, "1")
+		// Cookies do 304 continuam chegando ao jar
+		for _, c := range resp.Header.Values("Set-Cookie") {
+			cached.Header.Add("Set-Cookie", c)
+		}
+		return cached, nil
+	}
+
+	if req.Method == http.MethodGet && t.cache.cacheable(req, resp) {
+		maxEntry := t.cache.MaxEntrySize
+		if maxEntry <= 0 {
+			maxEntry = defaultMaxEntrySize
+		}
+		resp.Body = &cachingBody{
+			ReadCloser: resp.Body,
+			limit:      maxEntry,
+			onEOF: func(body []byte) {
+				t.cache.store(req, newCachedVariant(req, resp, body, requestTime, responseTime))
+			},
+		}
+	} else if req.Method == http.MethodGet && parseCacheControl(resp.Header).has("no-store") {
+		t.cache.Invalidate(req.URL)
+	}
+	return resp, nil
+}
+
+// invalidate remove a URL alvo e Location/Content-Location da mesma origem
+// após um método não seguro (RFC 9111, seção 4.4)
+func (t *cacheTransport) invalidate(req *http.Request, resp *http.Response) {
+	t.cache.Invalidate(req.URL)
+	for _, h := range []string{"Location", "Content-Location"} {
+		if loc := resp.Header.Get(h); loc != "" {
+			if u, err := req.URL.Parse(loc); err == nil && u.Host == req.URL.Host && u.Scheme == req.URL.Scheme {
+				t.cache.Invalidate(u)
+			}
+		}
+	}
+}
+
+func (t *cacheTransport) CloseIdleConnections() {
+	if transport, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// cachingBody copia o corpo enquanto é lido e armazena ao chegar no fim
+type cachingBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	limit    int64
+	overflow bool
+	done     bool
+	onEOF    func([]byte)
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.overflow {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflow = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !b.overflow && !b.done {
+		b.done = true
+		b.onEOF(b.buf.Bytes())
+	}
+	return n, err
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg, _ := strings.Cut(part, "=")
+			cc[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, ok := cc[directive]
+	if !ok || value == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+func parseHTTPDate(value string, fallback time.Time) time.Time {
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return fallback
+}
+
+// MemoryCache guarda as respostas em memória com descarte LRU
+type MemoryCache struct {
+	MaxBytes int64
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	size  int64
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache cria um cache em memória (maxBytes <= 0 usa 64 MB)
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	if maxBytes <= 0 {
+		maxBytes = defaultMemoryCacheSize
+	}
+	return &MemoryCache{
+		MaxBytes: maxBytes,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(*memoryCacheItem).value, true
+}
+
+func (m *MemoryCache) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		item := el.Value.(*memoryCacheItem)
+		m.size += int64(len(value) - len(item.value))
+		item.value = value
+		m.order.MoveToFront(el)
+	} else {
+		m.items[key] = m.order.PushFront(&memoryCacheItem{key: key, value: value})
+		m.size += int64(len(value))
+	}
+
+	for m.size > m.MaxBytes && m.order.Len() > 0 {
+		m.removeElement(m.order.Back())
+	}
+}
+
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		m.removeElement(el)
+	}
+}
+
+func (m *MemoryCache) removeElement(el *list.Element) {
+	item := m.order.Remove(el).(*memoryCacheItem)
+	delete(m.items, item.key)
+	m.size -= int64(len(item.value))
+}
+
+// DiskCache guarda cada URL em um arquivo do diretório, com descarte LRU
+// quando o total passa de MaxBytes. A ordem de uso é o mtime dos arquivos,
+// então sobrevive a reinícios do processo.
+type DiskCache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu    sync.Mutex
+	files map[string]*list.Element
+	order *list.List
+	size  int64
+}
+
+type diskCacheFile struct {
+	name string
+	size int64
+}
+
+// NewDiskCache cria (se preciso) o diretório do cache (maxBytes <= 0 usa 512 MB)
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	d := &DiskCache{Dir: dir, MaxBytes: maxBytes}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadIndex()
+	d.evict()
+	return d, nil
+}
+
+// loadIndex lê os arquivos existentes, do mais antigo para o mais recente
+func (d *DiskCache) loadIndex() {
+	d.files = make(map[string]*list.Element)
+	d.order = list.New()
+	d.size = 0
+
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		return
+	}
+	type indexed struct {
+		file  diskCacheFile
+		mtime time.Time
+	}
+	var found []indexed
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		// Sobras de escritas interrompidas
+		if strings.HasPrefix(entry.Name(), "tmp-") {
+			os.Remove(filepath.Join(d.Dir, entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, indexed{diskCacheFile{entry.Name(), info.Size()}, info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].mtime.Before(found[j].mtime) })
+	for _, f := range found {
+		file := f.file
+		d.files[file.name] = d.order.PushFront(&file)
+		d.size += file.size
+	}
+}
+
+func (d *DiskCache) maxBytes() int64 {
+	if d.MaxBytes <= 0 {
+		return defaultDiskCacheSize
+	}
+	return d.MaxBytes
+}
+
+// evict apaga os arquivos menos usados até caber em MaxBytes
+func (d *DiskCache) evict() {
+	for d.size > d.maxBytes() && d.order.Len() > 0 {
+		file := d.order.Back().Value.(*diskCacheFile)
+		os.Remove(filepath.Join(d.Dir, file.name))
+		d.removeFile(file.name)
+	}
+}
+
+func (d *DiskCache) removeFile(name string) {
+	if el, ok := d.files[name]; ok {
+		d.size -= d.order.Remove(el).(*diskCacheFile).size
+		delete(d.files, name)
+	}
+}
+
+func (d *DiskCache) name(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.order == nil {
+		d.loadIndex()
+	}
+
+	name := d.name(key)
+	path := filepath.Join(d.Dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		d.removeFile(name)
+		return nil, false
+	}
+	if el, ok := d.files[name]; ok {
+		d.order.MoveToFront(el)
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (d *DiskCache) Set(key string, value []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.order == nil {
+		d.loadIndex()
+	}
+
+	// Escrita atômica: leitores nunca veem um arquivo pela metade
+	tmp, err := os.CreateTemp(d.Dir, "tmp-*")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	tmp.Close()
+	name := d.name(key)
+	if err := os.Rename(tmp.Name(), filepath.Join(d.Dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	d.removeFile(name)
+	d.files[name] = d.order.PushFront(&diskCacheFile{name: name, size: int64(len(value))})
+	d.size += int64(len(value))
+	d.evict()
+}
+
+func (d *DiskCache) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.order == nil {
+		d.loadIndex()
+	}
+
+	name := d.name(key)
+	os.Remove(filepath.Join(d.Dir, name))
+	d.removeFile(name)
+}
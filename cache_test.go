@@ -0,0 +1,197 @@
+package browserclient
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPCacheIsFresh(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-60 * time.Second).Format(http.TimeFormat)
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		reqCC     string
+		heuristic bool
+		want      bool
+	}{
+		{"max-age fresh", http.Header{"Cache-Control": {"max-age=120"}, "Date": {date}}, "", true, true},
+		{"max-age stale", http.Header{"Cache-Control": {"max-age=30"}, "Date": {date}}, "", true, false},
+		{"age header counts", http.Header{"Cache-Control": {"max-age=120"}, "Date": {date}, "Age": {"90"}}, "", true, false},
+		{"expires fresh", http.Header{"Expires": {now.Add(time.Minute).Format(http.TimeFormat)}, "Date": {date}}, "", true, true},
+		{"invalid expires", http.Header{"Expires": {"0"}, "Date": {date}}, "", true, false},
+		{"max-age beats expires", http.Header{"Cache-Control": {"max-age=120"}, "Expires": {"0"}, "Date": {date}}, "", true, true},
+		{"response no-cache", http.Header{"Cache-Control": {"max-age=120, no-cache"}, "Date": {date}}, "", true, false},
+		{"request no-cache", http.Header{"Cache-Control": {"max-age=120"}, "Date": {date}}, "no-cache", true, false},
+		{"request max-age", http.Header{"Cache-Control": {"max-age=120"}, "Date": {date}}, "max-age=30", true, false},
+		{"request min-fresh", http.Header{"Cache-Control": {"max-age=120"}, "Date": {date}}, "min-fresh=90", true, false},
+		{"max-stale unbounded", http.Header{"Cache-Control": {"max-age=30"}, "Date": {date}}, "max-stale", true, true},
+		{"max-stale bounded", http.Header{"Cache-Control": {"max-age=30"}, "Date": {date}}, "max-stale=10", true, false},
+		{"must-revalidate beats max-stale", http.Header{"Cache-Control": {"max-age=30, must-revalidate"}, "Date": {date}}, "max-stale", true, false},
+		{
+			"heuristic from Last-Modified",
+			http.Header{"Date": {date}, "Last-Modified": {now.Add(-24 * time.Hour).Format(http.TimeFormat)}},
+			"", true, true,
+		},
+		{
+			"heuristic disabled",
+			http.Header{"Date": {date}, "Last-Modified": {now.Add(-24 * time.Hour).Format(http.TimeFormat)}},
+			"", false, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &HTTPCache{DisableHeuristic: !tt.heuristic}
+			v := &cachedVariant{
+				StatusCode:   200,
+				Header:       tt.header,
+				RequestTime:  now.Add(-60 * time.Second),
+				ResponseTime: now.Add(-60 * time.Second),
+			}
+			reqCC := parseCacheControl(http.Header{"Cache-Control": {tt.reqCC}})
+			if got := c.isFresh(v, reqCC, now); got != tt.want {
+				t.Errorf("isFresh() = %v, want %v (lifetime %v, age %v)", got, tt.want, c.freshnessLifetime(v), v.currentAge(now))
+			}
+		})
+	}
+}
+
+func TestHTTPCacheCacheable(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		reqCC  string
+		status int
+		header http.Header
+		want   bool
+	}{
+		{"max-age", http.MethodGet, "", 200, http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{"etag only", http.MethodGet, "", 200, http.Header{"Etag": {`"v1"`}}, true},
+		{"no validators", http.MethodGet, "", 200, http.Header{}, false},
+		{"post", http.MethodPost, "", 200, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"response no-store", http.MethodGet, "", 200, http.Header{"Cache-Control": {"no-store, max-age=60"}}, false},
+		{"request no-store", http.MethodGet, "no-store", 200, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"vary star", http.MethodGet, "", 200, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {"*"}}, false},
+		{"302 with max-age", http.MethodGet, "", 302, http.Header{"Cache-Control": {"max-age=60"}}, true},
+		{"302 heuristic only", http.MethodGet, "", 302, http.Header{"Last-Modified": {"Mon, 01 Jan 2024 00:00:00 GMT"}}, false},
+		{"500", http.MethodGet, "", 500, http.Header{"Cache-Control": {"max-age=60"}}, false},
+	}
+	c := &HTTPCache{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "https://example.com/a", nil)
+			if tt.reqCC != "" {
+				req.Header.Set("Cache-Control", tt.reqCC)
+			}
+			resp := &http.Response{StatusCode: tt.status, Header: tt.header, ContentLength: -1}
+			if got := c.cacheable(req, resp); got != tt.want {
+				t.Errorf("cacheable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedVariantVary(t *testing.T) {
+	stored, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+	stored.Header.Set("Accept-Encoding", "gzip,  br")
+	stored.Header.Set("Accept-Language", "pt-BR")
+	resp := &http.Response{StatusCode: 200, Header: http.Header{"Vary": {"accept-encoding, Accept-Language", "Origin"}}}
+	v := newCachedVariant(stored, resp, nil, time.Now(), time.Now())
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{"same headers", http.Header{"Accept-Encoding": {"gzip,  br"}, "Accept-Language": {"pt-BR"}}, true},
+		{"whitespace normalized", http.Header{"Accept-Encoding": {"gzip, br"}, "Accept-Language": {"pt-BR"}}, true},
+		{"different language", http.Header{"Accept-Encoding": {"gzip, br"}, "Accept-Language": {"en-US"}}, false},
+		{"origin added", http.Header{"Accept-Encoding": {"gzip, br"}, "Accept-Language": {"pt-BR"}, "Origin": {"https://x.test"}}, false},
+		{"missing header", http.Header{"Accept-Language": {"pt-BR"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+			req.Header = tt.header
+			if got := v.matches(req); got != tt.want {
+				t.Errorf("matches() = %v, want %v (vary %v)", got, tt.want, v.Vary)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestCacheTransportRevalidates(t *testing.T) {
+	var requests []*http.Request
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return &http.Response{StatusCode: 304, Header: http.Header{"Cache-Control": {"max-age=60"}}, Body: http.NoBody}, nil
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-cache"}},
+			Body:       io.NopCloser(strings.NewReader("hello")),
+		}, nil
+	})
+	transport := &cacheTransport{next: next, cache: NewHTTPCache(nil)}
+
+	get := func() *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/a", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "hello" {
+			t.Fatalf("body = %q", body)
+		}
+		return resp
+	}
+
+	// 1: rede; 2: no-cache obriga revalidar
+	if resp := get(); resp.Header.Get(CacheHeader) != "" {
+		t.Fatal("first response should come from the network")
+	}
+	if resp := get(); resp.Header.Get(CacheHeader) != "1" {
+		t.Fatal("304 should be served from the cache")
+	}
+	if len(requests) != 2 || requests[1].Header.Get("If-None-Match") != `"v1"` {
+		t.Fatalf("second request was not conditional: %d requests", len(requests))
+	}
+	// O Cache-Control do 304 substitui o armazenado: agora está fresco
+	if resp := get(); resp.Header.Get(CacheHeader) != "1" || len(requests) != 2 {
+		t.Fatalf("third response should be fresh from the cache (%d requests)", len(requests))
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	m := NewMemoryCache(10)
+	m.Set("a", []byte("12345"))
+	m.Set("b", []byte("12345"))
+	m.Get("a") // "a" passa a ser o mais recente
+	m.Set("c", []byte("12345"))
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := m.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%q) ok = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package browserclient
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ClientCertificate configura um certificado de cliente (mTLS).
+// Use CertFile/KeyFile (PEM) ou PKCS12File/PKCS12Password.
+type ClientCertificate struct {
+	// Hosts onde o certificado é apresentado (aceita "*.dominio").
+	// Vazio significa qualquer host.
+	Hosts []string
+
+	CertFile string
+	KeyFile  string
+
+	PKCS12File     string
+	PKCS12Password string
+
+	cert *utls.Certificate
+}
+
+// loadClientCertificates carrega todos os certificados de cliente configurados
+func loadClientCertificates(config *ClientConfig) error {
+	for i := range config.ClientCertificates {
+		cc := &config.ClientCertificates[i]
+		if cc.cert != nil {
+			continue
+		}
+
+		var cert utls.Certificate
+		var err error
+		switch {
+		case cc.PKCS12File != "":
+			cert, err = loadPKCS12(cc.PKCS12File, cc.PKCS12Password)
+		case cc.CertFile != "" && cc.KeyFile != "":
+			cert, err = utls.LoadX509KeyPair(cc.CertFile, cc.KeyFile)
+		default:
+			err = fmt.Errorf("missing certificate files")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate %d: %w", i, err)
+		}
+		cc.cert = &cert
+	}
+	return nil
+}
+
+// loadPKCS12 converte um arquivo .p12/.pfx em certificado com a cadeia inclusa.
+// Suporta tanto os algoritmos legados (3DES/RC2) quanto AES/PBES2, o padrão
+// do OpenSSL 3.
+func loadPKCS12(file, password string) (utls.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return utls.Certificate{}, err
+	}
+
+	key, first, rest, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return utls.Certificate{}, fmt.Errorf("failed to decode PKCS#12: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return utls.Certificate{}, fmt.Errorf("unsupported PKCS#12 private key type %T", key)
+	}
+
+	// Os bags vêm em qualquer ordem: a folha é o certificado da chave
+	certs := append([]*x509.Certificate{first}, rest...)
+	leaf := -1
+	for i, c := range certs {
+		if pub, ok := c.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && pub.Equal(signer.Public()) {
+			leaf = i
+			break
+		}
+	}
+	if leaf < 0 {
+		return utls.Certificate{}, fmt.Errorf("no certificate in PKCS#12 matches the private key")
+	}
+
+	cert := utls.Certificate{
+		Certificate: [][]byte{certs[leaf].Raw},
+		PrivateKey:  key,
+		Leaf:        certs[leaf],
+	}
+	for i, c := range certs {
+		if i != leaf {
+			cert.Certificate = append(cert.Certificate, c.Raw)
+		}
+	}
+	return cert, nil
+}
+
+// selectClientCertificate escolhe o primeiro certificado configurado para o
+// host que seja aceito pelo servidor
+func selectClientCertificate(config *ClientConfig, host string, info *utls.CertificateRequestInfo) *utls.Certificate {
+	for _, cc := range config.ClientCertificates {
+		if cc.cert == nil || !certMatchesHost(cc.Hosts, host) {
+			continue
+		}
+		if info.SupportsCertificate(cc.cert) == nil {
+			return cc.cert
+		}
+	}
+	return nil
+}
+
+func certMatchesHost(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+		if strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildGetClientCertificate retorna o callback de mTLS. O callback só é
+// consultado após o CertificateRequest do servidor, então o ClientHello
+// permanece idêntico ao do preset.
+func buildGetClientCertificate(host string, config *ClientConfig) func(*utls.CertificateRequestInfo) (*utls.Certificate, error) {
+	if len(config.ClientCertificates) == 0 {
+		return nil
+	}
+	return func(info *utls.CertificateRequestInfo) (*utls.Certificate, error) {
+		if cert := selectClientCertificate(config, host, info); cert != nil {
+			return cert, nil
+		}
+		// Sem certificado adequado: responder com certificado vazio
+		return &utls.Certificate{}, nil
+	}
+}
@@ -0,0 +1,291 @@
+package browserclient
+
+import (
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Ordem em que o Chrome escreve os client hints de alta entropia
+var highEntropyHintOrder = []string{
+	"Device-Memory",
+	"Sec-Ch-Device-Memory",
+	"Dpr",
+	"Sec-Ch-Dpr",
+	"Viewport-Width",
+	"Sec-Ch-Viewport-Width",
+	"Sec-Ch-Viewport-Height",
+	"Rtt",
+	"Downlink",
+	"Ect",
+	"Sec-Ch-Prefers-Color-Scheme",
+	"Sec-Ch-Prefers-Reduced-Motion",
+	"Sec-Ch-Ua-Full-Version",
+	"Sec-Ch-Ua-Arch",
+	"Sec-Ch-Ua-Platform-Version",
+	"Sec-Ch-Ua-Model",
+	"Sec-Ch-Ua-Bitness",
+	"Sec-Ch-Ua-Wow64",
+	"Sec-Ch-Ua-Full-Version-List",
+	"Sec-Ch-Ua-Form-Factors",
+}
+
+// clientHintsStore guarda, por origem, os hints pedidos via Accept-CH
+type clientHintsStore struct {
+	mu      sync.RWMutex
+	origins map[string][]string
+}
+
+func newClientHintsStore() *clientHintsStore {
+	return &clientHintsStore{origins: make(map[string][]string)}
+}
+
+func (s *clientHintsStore) get(origin string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.origins[origin]
+}
+
+// set substitui a lista da origem; Accept-CH vazio limpa
+func (s *clientHintsStore) set(origin string, hints []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(hints) == 0 {
+		delete(s.origins, origin)
+		return
+	}
+	s.origins[origin] = hints
+}
+
+func (s *clientHintsStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.origins = make(map[string][]string)
+}
+
+// ClientHints retorna os hints que a origem pediu via Accept-CH
+func (bc *BrowserClient) ClientHints(origin string) []string {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil
+	}
+	return append([]string(nil), bc.clientHints.get(originOf(u))...)
+}
+
+// ClearClientHints esquece os Accept-CH de todas as origens
+func (bc *BrowserClient) ClearClientHints() {
+	bc.clientHints.clear()
+}
+
+// observeAcceptCH registra o Accept-CH de uma resposta de navegação. Como no
+// Chrome, só origens seguras (https ou localhost) podem pedir hints.
+func (bc *BrowserClient) observeAcceptCH(resp *http.Response) {
+	if resp == nil || resp.Request == nil || !sendsClientHints(bc.profile) {
+		return
+	}
+	values := resp.Header.Values("Accept-Ch")
+	if values == nil || !isSecureOrigin(resp.Request.URL) {
+		return
+	}
+
+	var hints []string
+	for _, name := range parseHintList(strings.Join(values, ",")) {
+		if _, ok := clientHintValue(bc.profile, name); ok {
+			hints = append(hints, name)
+		}
+	}
+	bc.clientHints.set(originOf(resp.Request.URL), hints)
+}
+
+// needsCriticalCHRestart indica que a resposta pediu em Critical-CH um hint
+// habilitado para a origem mas ausente da requisição
+func (bc *BrowserClient) needsCriticalCHRestart(resp *http.Response) bool {
+	critical := resp.Header.Get("Critical-Ch")
+	if critical == "" || resp.Request == nil || !sendsClientHints(bc.profile) {
+		return false
+	}
+
+	enabled := bc.clientHints.get(originOf(resp.Request.URL))
+	for _, name := range parseHintList(critical) {
+		if containsHint(enabled, name) && resp.Request.Header.Get(name) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// restartForCriticalCH repete a navegação desde a URL original, agora com
+// os hints pedidos, como o Chrome faz uma única vez por navegação
+func (bc *BrowserClient) restartForCriticalCH(client *http.Client, req *http.Request, resp *http.Response, opts RequestOptions) (*http.Response, error) {
+	if !opts.IsNavigate || !bc.needsCriticalCHRestart(resp) {
+		return resp, nil
+	}
+
+	restart, err := RewindRequest(req)
+	if err != nil {
+		// Sem como reenviar o corpo: fica a resposta original
+		return resp, nil
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	bc.applyHeaders(restart, opts)
+	return client.Do(withConnectionInfo(restart))
+}
+
+// addClientHints inclui os hints de alta entropia pedidos pela origem
+func (hb *HeaderBuilder) addClientHints(headers map[string][]string, u *url.URL) {
+	if hb.hints == nil || u == nil || !isSecureOrigin(u) {
+		return
+	}
+	for _, name := range hb.hints.get(originOf(u)) {
+		if value, ok := clientHintValue(hb.profile, name); ok {
+			headers[name] = []string{value}
+		}
+	}
+}
+
+// clientHintValue deriva o valor do hint do perfil; false para hints
+// desconhecidos ou de baixa entropia (esses são sempre enviados)
+func clientHintValue(profile *BrowserProfile, name string) (string, bool) {
+	platform := uaPlatform(profile.UserAgent)
+
+	switch http.CanonicalHeaderKey(name) {
+	case "Sec-Ch-Ua-Full-Version-List":
+		return profileBrandList(profile, true), true
+	case "Sec-Ch-Ua-Full-Version":
+		return strconv.Quote(brandVersion(profileBrowser(profile), profile.UserAgent, true)), true
+	case "Sec-Ch-Ua-Arch":
+		// O Chrome do Android não informa arquitetura nem bitness
+		if profile.Mobile {
+			return `""`, true
+		}
+		if platform == "macOS" && profileChoice(profile, 4) != 0 {
+			return `"arm"`, true
+		}
+		return `"x86"`, true
+	case "Sec-Ch-Ua-Bitness":
+		if profile.Mobile {
+			return `""`, true
+		}
+		return `"64"`, true
+	case "Sec-Ch-Ua-Model":
+		return strconv.Quote(profile.Model), true
+	case "Sec-Ch-Ua-Wow64":
+		return "?0", true
+	case "Sec-Ch-Ua-Form-Factors":
+		if profile.Mobile {
+			return `"Mobile"`, true
+		}
+		return `"Desktop"`, true
+	case "Sec-Ch-Ua-Platform-Version":
+		return strconv.Quote(platformVersion(profile, platform)), true
+	case "Sec-Ch-Viewport-Width", "Viewport-Width":
+		return strconv.Itoa(profile.ViewportWidth), true
+	case "Sec-Ch-Viewport-Height":
+		return strconv.Itoa(profile.ViewportHeight), true
+	case "Sec-Ch-Dpr", "Dpr":
+		return strconv.FormatFloat(float64(profile.PixelRatio), 'f', -1, 32), true
+	case "Sec-Ch-Device-Memory", "Device-Memory":
+		return "8", true
+	case "Rtt":
+		return "50", true
+	case "Downlink":
+		return "10", true
+	case "Ect":
+		return "4g", true
+	case "Sec-Ch-Prefers-Color-Scheme":
+		return "light", true
+	case "Sec-Ch-Prefers-Reduced-Motion":
+		return "no-preference", true
+	}
+	return "", false
+}
+
+// platformVersion segue o que o Chrome reporta: no Windows, 10.0.0 para o
+// Windows 10 e 13+ para o 11; no macOS, a versão real (o UA fica congelado)
+func platformVersion(profile *BrowserProfile, platform string) string {
+	switch platform {
+	case "Windows":
+		return []string{"10.0.0", "15.0.0", "15.0.0", "19.0.0"}[profileChoice(profile, 4)]
+	case "macOS":
+		return []string{"13.6.7", "14.5.0", "14.6.1", "15.0.1"}[profileChoice(profile, 4)]
+	case "Linux":
+		return "6.5.0"
+	case "Android":
+		return []string{"13.0.0", "14.0.0", "14.0.0", "15.0.0"}[profileChoice(profile, 4)]
+	}
+	return ""
+}
+
+// profileChoice escolhe de forma estável por perfil entre n opções
+func profileChoice(profile *BrowserProfile, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(profile.SessionID))
+	return int(h.Sum32() % uint32(n))
+}
+
+// uaPlatform é o Sec-Ch-Ua-Platform correspondente ao User-Agent
+func uaPlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		return "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		return "iOS"
+	case strings.Contains(userAgent, "Macintosh"):
+		return "macOS"
+	case strings.Contains(userAgent, "X11"):
+		return "Linux"
+	}
+	return "Windows"
+}
+
+// sendsClientHints indica se o navegador do perfil implementa client hints
+func sendsClientHints(profile *BrowserProfile) bool {
+	switch profileBrowser(profile) {
+	case "Chrome", "Edge", "Opera", "Brave", "Samsung":
+		return true
+	}
+	return false
+}
+
+func parseHintList(value string) []string {
+	var hints []string
+	for _, part := range strings.Split(value, ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			hints = append(hints, http.CanonicalHeaderKey(name))
+		}
+	}
+	return hints
+}
+
+func containsHint(hints []string, name string) bool {
+	for _, h := range hints {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func originOf(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// isSecureOrigin segue os "potentially trustworthy origins" do Chrome
+func isSecureOrigin(u *url.URL) bool {
+	if u.Scheme == "https" {
+		return true
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ItalinhoGO/browserclient"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// cookieFile é um arquivo de cookies no formato Netscape, o mesmo do curl
+type cookieFile struct {
+	cookies []fileCookie
+}
+
+type fileCookie struct {
+	domain     string
+	subdomains bool
+	path       string
+	secure     bool
+	httpOnly   bool
+	// 0 é cookie de sessão
+	expires int64
+	name    string
+	value   string
+}
+
+func (c fileCookie) key() string {
+	return c.domain + "\t" + c.path + "\t" + c.name
+}
+
+func loadCookieFile(name string) (*cookieFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Como no curl, -b e -c podem apontar para um arquivo ainda inexistente
+			return &cookieFile{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	jar := &cookieFile{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		if httpOnly {
+			text = strings.TrimPrefix(text, httpOnlyPrefix)
+		} else if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab-separated fields", name, line)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry %q", name, line, fields[4])
+		}
+		jar.cookies = append(jar.cookies, fileCookie{
+			domain:     fields[0],
+			subdomains: strings.EqualFold(fields[1], "TRUE"),
+			path:       fields[2],
+			secure:     strings.EqualFold(fields[3], "TRUE"),
+			httpOnly:   httpOnly,
+			expires:    expires,
+			name:       fields[5],
+			value:      fields[6],
+		})
+	}
+	return jar, scanner.Err()
+}
+
+// apply coloca os cookies ainda válidos no jar do cliente
+func (f *cookieFile) apply(bc *browserclient.BrowserClient) error {
+	now := time.Now().Unix()
+	for _, c := range f.cookies {
+		if c.expires != 0 && c.expires < now {
+			continue
+		}
+		host := strings.TrimPrefix(c.domain, ".")
+		scheme := "http"
+		if c.secure {
+			scheme = "https"
+		}
+		cookie := &http.Cookie{
+			Name:     c.name,
+			Value:    c.value,
+			Path:     c.path,
+			Secure:   c.secure,
+			HttpOnly: c.httpOnly,
+		}
+		if c.subdomains {
+			cookie.Domain = host
+		}
+		if c.expires != 0 {
+			cookie.Expires = time.Unix(c.expires, 0)
+		}
+		if err := bc.SetCookie(scheme+"://"+host+"/", cookie); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge aplica os Set-Cookie de todos os saltos gravados no HAR
+func (f *cookieFile) merge(har *browserclient.HAR, now time.Time) {
+	for _, e := range har.Log.Entries {
+		reqURL, err := url.Parse(e.Request.URL)
+		if err != nil {
+			continue
+		}
+		for _, h := range e.Response.Headers {
+			if !strings.EqualFold(h.Name, "Set-Cookie") {
+				continue
+			}
+			cookie, err := http.ParseSetCookie(h.Value)
+			if err != nil {
+				continue
+			}
+			f.set(fromSetCookie(reqURL, cookie, now), now)
+		}
+	}
+}
+
+func fromSetCookie(reqURL *url.URL, cookie *http.Cookie, now time.Time) fileCookie {
+	c := fileCookie{
+		domain:   reqURL.Hostname(),
+		path:     cookie.Path,
+		secure:   cookie.Secure,
+		httpOnly: cookie.HttpOnly,
+		name:     cookie.Name,
+		value:    cookie.Value,
+	}
+	if cookie.Domain != "" {
+		c.domain = "." + strings.TrimPrefix(cookie.Domain, ".")
+		c.subdomains = true
+	}
+	if c.path == "" || !strings.HasPrefix(c.path, "/") {
+		// Path padrão da RFC 6265, seção 5.1.4
+		c.path = path.Dir(reqURL.EscapedPath())
+		if c.path == "." {
+			c.path = "/"
+		}
+	}
+
+	switch {
+	case cookie.MaxAge < 0:
+		c.expires = -1
+	case cookie.MaxAge > 0:
+		c.expires = now.Unix() + int64(cookie.MaxAge)
+	case !cookie.Expires.IsZero():
+		c.expires = cookie.Expires.Unix()
+		if c.expires <= 0 {
+			c.expires = -1
+		}
+	}
+	return c
+}
+
+// set substitui o cookie de mesmo domínio/path/nome; expirados são removidos
+func (f *cookieFile) set(c fileCookie, now time.Time) {
+	kept := f.cookies[:0]
+	for _, existing := range f.cookies {
+		if existing.key() != c.key() {
+			kept = append(kept, existing)
+		}
+	}
+	f.cookies = kept
+	if c.expires == 0 || c.expires > now.Unix() {
+		f.cookies = append(f.cookies, c)
+	}
+}
+
+func (f *cookieFile) save(name string) error {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n# Written by bcurl\n\n")
+	for _, c := range f.cookies {
+		if c.httpOnly {
+			b.WriteString(httpOnlyPrefix)
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			c.domain, netscapeBool(c.subdomains), c.path, netscapeBool(c.secure), c.expires, c.name, c.value)
+	}
+	return os.WriteFile(name, []byte(b.String()), 0o600)
+}
+
+func netscapeBool(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,50 @@
+// hardiff compara as requisições de um HAR exportado pelo navegador com um
+// HAR gravado pelo HARRecorder.
+//
+//	hardiff [-critical] browser.har ours.har
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ItalinhoGO/browserclient"
+)
+
+func main() {
+	critical := flag.Bool("critical", false, "exit with status 1 when a critical header value differs")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: hardiff [-critical] browser.har ours.har\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	browser, err := browserclient.LoadHAR(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ours, err := browserclient.LoadHAR(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	report := browserclient.DiffHAR(browser, ours)
+	report.WriteTo(os.Stdout)
+
+	if *critical {
+		for _, d := range report.Requests {
+			for _, v := range d.Values {
+				if v.Critical {
+					os.Exit(1)
+				}
+			}
+		}
+	}
+}
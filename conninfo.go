@@ -0,0 +1,164 @@
+package browserclient
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// ConnectionInfo descreve a conexão que atendeu uma resposta
+type ConnectionInfo struct {
+	TLSVersion       uint16
+	CipherSuite      uint16
+	ALPN             string
+	DidResume        bool
+	ECHAccepted      bool
+	ClientHelloID    utls.ClientHelloID
+	PeerCertificates []*x509.Certificate
+
+	RemoteAddr string
+	RemoteIP   net.IP
+	LocalAddr  string
+	LocalIP    net.IP
+	Proxy      string
+	Reused     bool
+
+	Timing ConnectionTiming
+
+	helloSpec *utls.ClientHelloSpec
+}
+
+// ConnectionTiming contém os tempos da conexão e da requisição.
+// Em conexões reutilizadas, DNS/Connect/TLSHandshake são os da abertura original.
+type ConnectionTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	TTFB         time.Duration
+}
+
+type connInfoKey struct{}
+
+// connInfoTracker acumula os eventos de httptrace da requisição
+type connInfoTracker struct {
+	mu      sync.Mutex
+	info    *ConnectionInfo
+	started time.Time
+}
+
+// GetConnectionInfo retorna as informações de conexão de uma resposta obtida
+// pelo BrowserClient, ou nil se não houver
+func GetConnectionInfo(resp *http.Response) *ConnectionInfo {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	tracker, ok := resp.Request.Context().Value(connInfoKey{}).(*connInfoTracker)
+	if !ok {
+		return nil
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.info
+}
+
+// ClientHelloSpec retorna o spec efetivamente enviado no ClientHello
+func (ci *ConnectionInfo) ClientHelloSpec() (*utls.ClientHelloSpec, error) {
+	if ci.helloSpec != nil {
+		return ci.helloSpec, nil
+	}
+	spec, err := utls.UTLSIdToSpec(ci.ClientHelloID)
+	if err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (ci *ConnectionInfo) String() string {
+	if ci.TLSVersion == 0 {
+		return fmt.Sprintf("remote=%s local=%s proxy=%q reused=%v ttfb=%s", ci.RemoteAddr, ci.LocalAddr, ci.Proxy, ci.Reused, ci.Timing.TTFB)
+	}
+	return fmt.Sprintf("remote=%s local=%s proxy=%q tls=%s cipher=%s alpn=%s resumed=%v ech=%v hello=%s reused=%v dns=%s connect=%s tls_handshake=%s ttfb=%s",
+		ci.RemoteAddr, ci.LocalAddr, ci.Proxy, utls.VersionName(ci.TLSVersion), utls.CipherSuiteName(ci.CipherSuite), ci.ALPN,
+		ci.DidResume, ci.ECHAccepted, ci.ClientHelloID.Str(), ci.Reused,
+		ci.Timing.DNS, ci.Timing.Connect, ci.Timing.TLSHandshake, ci.Timing.TTFB)
+}
+
+// withConnectionInfo instrumenta a requisição para registrar a conexão usada.
+// Redirects herdam o contexto, então a informação final é a da última resposta.
+func withConnectionInfo(req *http.Request) *http.Request {
+	tracker := &connInfoTracker{}
+
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			tracker.mu.Lock()
+			tracker.started = time.Now()
+			tracker.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			ci := newConnectionInfo(info.Conn)
+			ci.Reused = info.Reused
+			tracker.mu.Lock()
+			tracker.info = ci
+			tracker.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tracker.mu.Lock()
+			if tracker.info != nil && !tracker.started.IsZero() {
+				tracker.info.Timing.TTFB = time.Since(tracker.started)
+			}
+			tracker.mu.Unlock()
+		},
+	}
+
+	ctx := context.WithValue(req.Context(), connInfoKey{}, tracker)
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace))
+}
+
+func newConnectionInfo(conn net.Conn) *ConnectionInfo {
+	ci := &ConnectionInfo{}
+	if conn == nil {
+		return ci
+	}
+
+	if addr := conn.RemoteAddr(); addr != nil {
+		ci.RemoteAddr = addr.String()
+		if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+			ci.RemoteIP = tcpAddr.IP
+		}
+	}
+	if addr := conn.LocalAddr(); addr != nil {
+		ci.LocalAddr = addr.String()
+		if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+			ci.LocalIP = tcpAddr.IP
+		}
+	}
+
+	switch c := conn.(type) {
+	case *tlsConn:
+		state := c.ConnectionState()
+		ci.TLSVersion = state.Version
+		ci.CipherSuite = state.CipherSuite
+		ci.ALPN = state.NegotiatedProtocol
+		ci.DidResume = state.DidResume
+		ci.ECHAccepted = state.ECHAccepted
+		ci.PeerCertificates = state.PeerCertificates
+		ci.ClientHelloID = c.helloID
+		ci.helloSpec = c.helloSpec
+		ci.Timing.DNS = c.timing.DNS
+		ci.Timing.Connect = c.timing.Connect
+		ci.Timing.TLSHandshake = c.timing.TLSHandshake
+		ci.Proxy = c.proxy
+	case *timedConn:
+		ci.Timing.DNS = c.timing.DNS
+		ci.Timing.Connect = c.timing.Connect
+		ci.Proxy = c.proxy
+	}
+	return ci
+}
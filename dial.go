@@ -0,0 +1,304 @@
+package browserclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 10 * time.Second
+	// Connection Attempt Delay recomendado pela RFC 8305, seção 5
+	defaultHappyEyeballsDelay = 250 * time.Millisecond
+	minHappyEyeballsDelay     = 10 * time.Millisecond
+)
+
+// AddressFamily controla quais famílias de endereço são usadas nos dials
+type AddressFamily int
+
+const (
+	// FamilyAuto usa IPv6 e IPv4 com Happy Eyeballs v2 (RFC 8305)
+	FamilyAuto AddressFamily = iota
+	// FamilyIPv4Only conecta apenas em endereços IPv4
+	FamilyIPv4Only
+	// FamilyIPv6Only conecta apenas em endereços IPv6
+	FamilyIPv6Only
+	// FamilyPreferIPv4 faz Happy Eyeballs começando por IPv4
+	FamilyPreferIPv4
+)
+
+// connTiming guarda os tempos de estabelecimento de uma conexão
+type connTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+}
+
+// timedConn é uma conexão TCP simples com os tempos de abertura
+type timedConn struct {
+	net.Conn
+	timing connTiming
+	proxy  string
+}
+
+// dialTimed resolve o host e conecta com Happy Eyeballs, medindo
+// separadamente DNS e TCP connect
+func dialTimed(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile) (net.Conn, connTiming, error) {
+	var timing connTiming
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, timing, err
+	}
+
+	var ips []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IPAddr{{IP: ip}}
+	} else {
+		dnsStart := time.Now()
+		ips, err = getResolver(config).LookupIPAddr(ctx, host)
+		timing.DNS = time.Since(dnsStart)
+		if err != nil {
+			return nil, timing, err
+		}
+	}
+
+	ips = sortAddresses(filterAddresses(ips, network, config.AddressFamily), config.AddressFamily)
+	if len(ips) == 0 {
+		return nil, timing, fmt.Errorf("no suitable addresses for %s", host)
+	}
+
+	timeout := config.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	connectStart := time.Now()
+	conn, err := happyEyeballsDial(dialCtx, network, ips, port, config, profile)
+	if err != nil {
+		return nil, timing, err
+	}
+	timing.Connect = time.Since(connectStart)
+	return conn, timing, nil
+}
+
+// filterAddresses remove endereços fora da família configurada
+func filterAddresses(ips []net.IPAddr, network string, family AddressFamily) []net.IPAddr {
+	wantV4 := family != FamilyIPv6Only && network != "tcp6"
+	wantV6 := family != FamilyIPv4Only && network != "tcp4"
+
+	filtered := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		isV4 := ip.IP.To4() != nil
+		if (isV4 && wantV4) || (!isV4 && wantV6) {
+			filtered = append(filtered, ip)
+		}
+	}
+	return filtered
+}
+
+// sortAddresses intercala as famílias começando pela preferida (RFC 8305, seção 4)
+func sortAddresses(ips []net.IPAddr, family AddressFamily) []net.IPAddr {
+	var v4, v6 []net.IPAddr
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	first, second := v6, v4
+	if family == FamilyPreferIPv4 {
+		first, second = v4, v6
+	}
+
+	sorted := make([]net.IPAddr, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			sorted = append(sorted, first[i])
+		}
+		if i < len(second) {
+			sorted = append(sorted, second[i])
+		}
+	}
+	return sorted
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// happyEyeballsDial inicia uma tentativa a cada HappyEyeballsDelay (ou
+// imediatamente quando a anterior falha) e fica com a primeira que conectar
+func happyEyeballsDial(ctx context.Context, network string, ips []net.IPAddr, port string, config *ClientConfig, profile *BrowserProfile) (net.Conn, error) {
+	delay := config.HappyEyeballsDelay
+	if delay <= 0 {
+		delay = defaultHappyEyeballsDelay
+	}
+	if delay < minHappyEyeballsDelay {
+		delay = minHappyEyeballsDelay
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(ips))
+	attempt := func(ip net.IPAddr) {
+		conn, err := dialAddress(raceCtx, network, ip, port, config, profile)
+		results <- dialResult{conn, err}
+	}
+
+	next, inFlight := 0, 0
+	var lastErr error
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		if next < len(ips) && inFlight == 0 {
+			// Nada em andamento: não há por que esperar o delay
+			go attempt(ips[next])
+			next++
+			inFlight++
+			timer.Reset(delay)
+		}
+
+		select {
+		case res := <-results:
+			inFlight--
+			if res.err == nil {
+				cancel()
+				// Fechar conexões que ainda concluírem depois da vencedora
+				go drainDialResults(results, inFlight)
+				return res.conn, nil
+			}
+			lastErr = res.err
+			if next >= len(ips) && inFlight == 0 {
+				return nil, fmt.Errorf("all connection attempts failed: %w", lastErr)
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				go attempt(ips[next])
+				next++
+				inFlight++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			go drainDialResults(results, inFlight)
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w (last error: %v)", ctx.Err(), lastErr)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func drainDialResults(results <-chan dialResult, pending int) {
+	for i := 0; i < pending; i++ {
+		if res := <-results; res.conn != nil {
+			res.conn.Close()
+		}
+	}
+}
+
+// dialAddress conecta em um único endereço, com o bind de origem configurado
+func dialAddress(ctx context.Context, network string, ip net.IPAddr, port string, config *ClientConfig, profile *BrowserProfile) (net.Conn, error) {
+	local, err := localAddrFor(config, profile, ip.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{KeepAlive: 30 * time.Second}
+	if local != nil {
+		dialer.LocalAddr = local
+	}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+
+	if pool := config.LocalAddrPool; pool != nil && local != nil {
+		if err == nil {
+			pool.MarkHealthy(local.IP)
+		} else if ctx.Err() == nil {
+			pool.markDialFailed(local.IP, ip.IP, err)
+		}
+	}
+	return conn, err
+}
+
+// localAddrFor escolhe o endereço de origem da mesma família do destino:
+// do LocalAddrPool, de LocalAddr ou do primeiro endereço da interface
+func localAddrFor(config *ClientConfig, profile *BrowserProfile, remote net.IP) (*net.TCPAddr, error) {
+	remoteV4 := remote.To4() != nil
+
+	if config.LocalAddrPool != nil {
+		ip := config.LocalAddrPool.pick(profile.SessionID, remoteV4)
+		if ip == nil {
+			return nil, fmt.Errorf("no healthy local address for %s", remote)
+		}
+		return &net.TCPAddr{IP: ip}, nil
+	}
+
+	if config.LocalAddr != "" {
+		ip := net.ParseIP(config.LocalAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address %q", config.LocalAddr)
+		}
+		if (ip.To4() != nil) != remoteV4 {
+			return nil, fmt.Errorf("local address %s cannot reach %s", ip, remote)
+		}
+		return &net.TCPAddr{IP: ip}, nil
+	}
+
+	if config.Interface != "" {
+		iface, err := net.InterfaceByName(config.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", config.Interface, err)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", config.Interface, err)
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if (ipNet.IP.To4() != nil) == remoteV4 {
+				return &net.TCPAddr{IP: ipNet.IP}, nil
+			}
+		}
+		return nil, fmt.Errorf("interface %s has no address for %s", config.Interface, remote)
+	}
+
+	return nil, nil
+}
+
+// dialPlain é usado pelo transport para conexões HTTP sem TLS. Com proxy,
+// addr já é o do proxy (o http.Transport encaminha a requisição para ele).
+func dialPlain(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile, proxies *ProxyPool) (net.Conn, error) {
+	var entry *proxyEntry
+	if proxies != nil {
+		entry = proxies.findByAddr(addr)
+	}
+
+	conn, timing, err := dialTimed(ctx, network, addr, config, profile)
+	if entry != nil {
+		if err == nil {
+			proxies.markSuccess(entry)
+		} else if ctx.Err() == nil {
+			proxies.markFailed(entry)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &timedConn{Conn: conn, timing: timing}
+	if entry != nil {
+		tc.proxy = entry.name
+	}
+	return tc, nil
+}
@@ -0,0 +1,243 @@
+package browserclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultECHDNSServer = "1.1.1.1:53"
+	echRetryConfigTTL   = 1 * time.Hour
+	echNegativeTTL      = 5 * time.Minute
+
+	// Tipo de registro DNS HTTPS (RFC 9460) e chave do parâmetro "ech"
+	dnsTypeHTTPS   dnsmessage.Type = 65
+	svcParamKeyECH uint16          = 5
+)
+
+// ECHConfigList por resolver e host, obtidos do DNS ou de retry_configs do
+// servidor
+var echConfigCache sync.Map
+
+// echCacheKey separa clientes com configurações de DNS diferentes
+type echCacheKey struct {
+	resolver dnsExchanger
+	host     string
+}
+
+type echCacheEntry struct {
+	configList []byte
+	expires    time.Time
+}
+
+// getECHConfigList resolve o ECHConfigList de um host, na ordem:
+// configuração estática por host, global e por fim registros DNS HTTPS.
+// Com proxy, o DNS só é consultado por DoH/DoT: UDP direto revelaria o host
+// fora do túnel.
+func getECHConfigList(ctx context.Context, host string, config *ClientConfig, proxied bool) []byte {
+	if list, ok := config.ECHConfigs[host]; ok {
+		return list
+	}
+	if len(config.ECHConfigList) > 0 {
+		return config.ECHConfigList
+	}
+	if !config.ECHFromDNS || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	ex := encryptedDNSExchanger(config)
+	if ex == nil {
+		if proxied {
+			return nil
+		}
+		ex = dnsExchangerFor(config)
+	}
+	key := echCacheKey{resolver: ex, host: host}
+
+	if cached, ok := echConfigCache.Load(key); ok {
+		entry := cached.(*echCacheEntry)
+		if time.Now().Before(entry.expires) {
+			return entry.configList
+		}
+	}
+
+	list, ttl, err := lookupECHConfigList(ctx, host, ex)
+	if err != nil {
+		// Evita consultar o DNS a cada conexão quando o host não publica ECH
+		storeECHConfigList(key, nil, echNegativeTTL)
+		return nil
+	}
+	storeECHConfigList(key, list, ttl)
+	return list
+}
+
+func storeECHConfigList(key echCacheKey, list []byte, ttl time.Duration) {
+	echConfigCache.Store(key, &echCacheEntry{
+		configList: list,
+		expires:    time.Now().Add(ttl),
+	})
+}
+
+// echStatusStore guarda, por host, o resultado do último handshake com ECH
+// real de um cliente (compartilhado pelos transports dos seus proxies)
+type echStatusStore struct {
+	results sync.Map
+}
+
+func (s *echStatusStore) record(host string, accepted bool) {
+	s.results.Store(host, accepted)
+}
+
+// ECHStatus informa se o último handshake com o host ofereceu ECH real
+// e se o servidor o aceitou
+func (bc *BrowserClient) ECHStatus(host string) (offered, accepted bool) {
+	if v, ok := bc.echStatus.results.Load(host); ok {
+		return true, v.(bool)
+	}
+	return false, false
+}
+
+// fingerprintSupportsECH verifica se o ClientHello do preset contém a
+// extensão ECH (GREASE), que o uTLS substitui pela real quando há config
+func fingerprintSupportsECH(fingerprint utls.ClientHelloID) bool {
+	spec, err := utls.UTLSIdToSpec(fingerprint)
+	if err != nil {
+		return false
+	}
+	for _, ext := range spec.Extensions {
+		if _, ok := ext.(utls.EncryptedClientHelloExtension); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupECHConfigList consulta o registro HTTPS do host e extrai o parâmetro ech
+func lookupECHConfigList(ctx context.Context, host string, ex dnsExchanger) ([]byte, time.Duration, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err := buildDNSQuery(name, dnsTypeHTTPS)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := ex.exchange(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseHTTPSRecordECH(resp)
+}
+
+func dnsFQDN(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
+
+func buildDNSQuery(name dnsmessage.Name, qtype dnsmessage.Type) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               uint16(rand.Intn(1 << 16)),
+		RecursionDesired: true,
+	})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  qtype,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// parseHTTPSRecordECH extrai o ECHConfigList do primeiro registro HTTPS
+// em modo serviço (SvcPriority > 0) que o contenha
+func parseHTTPSRecordECH(msg []byte) ([]byte, time.Duration, error) {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(msg); err != nil {
+		return nil, 0, err
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	for {
+		header, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if header.Type != dnsTypeHTTPS {
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		res, err := parser.UnknownResource()
+		if err != nil {
+			return nil, 0, err
+		}
+		if list := parseSvcParamECH(res.Data); list != nil {
+			return list, time.Duration(header.TTL) * time.Second, nil
+		}
+	}
+
+	return nil, 0, errors.New("no ECH config in HTTPS records")
+}
+
+// parseSvcParamECH percorre o RDATA de um registro HTTPS (RFC 9460, seção 2.2)
+func parseSvcParamECH(data []byte) []byte {
+	if len(data) < 2 || binary.BigEndian.Uint16(data) == 0 {
+		// Modo alias não carrega parâmetros
+		return nil
+	}
+	data = data[2:]
+
+	// TargetName em formato wire, sem compressão
+	for {
+		if len(data) == 0 {
+			return nil
+		}
+		labelLen := int(data[0])
+		data = data[1:]
+		if labelLen == 0 {
+			break
+		}
+		if len(data) < labelLen {
+			return nil
+		}
+		data = data[labelLen:]
+	}
+
+	for len(data) >= 4 {
+		key := binary.BigEndian.Uint16(data)
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		data = data[4:]
+		if len(data) < length {
+			return nil
+		}
+		if key == svcParamKeyECH {
+			return append([]byte(nil), data[:length]...)
+		}
+		data = data[length:]
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package browserclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// svcRDATA monta o RDATA de um registro HTTPS: prioridade, target e SvcParams
+func svcRDATA(priority uint16, target []byte, params ...[]byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, priority)
+	b = append(b, target...)
+	for _, p := range params {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func svcParam(key uint16, value []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, key)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+	return append(b, value...)
+}
+
+func TestParseSvcParamECH(t *testing.T) {
+	echList := []byte{0x00, 0x04, 0xfe, 0x0d, 0x00, 0x00}
+	alpn := svcParam(1, []byte{2, 'h', '2'})
+	root := []byte{0}
+	target := []byte{3, 'c', 'd', 'n', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"ech after alpn", svcRDATA(1, root, alpn, svcParam(svcParamKeyECH, echList)), echList},
+		{"ech with target name", svcRDATA(1, target, svcParam(svcParamKeyECH, echList)), echList},
+		{"no ech", svcRDATA(1, root, alpn), nil},
+		{"alias mode", svcRDATA(0, target, svcParam(svcParamKeyECH, echList)), nil},
+		{"truncated param", svcRDATA(1, root, svcParam(svcParamKeyECH, echList)[:6]), nil},
+		{"truncated target", svcRDATA(1, target[:5]), nil},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSvcParamECH(tt.data); !bytes.Equal(got, tt.want) {
+				t.Errorf("parseSvcParamECH() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTTPSRecordECH(t *testing.T) {
+	name := dnsmessage.MustNewName("example.com.")
+	echList := []byte{0x00, 0x04, 0xfe, 0x0d, 0x00, 0x00}
+
+	build := func(answers ...func(b *dnsmessage.Builder) error) []byte {
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+		b.StartQuestions()
+		b.Question(dnsmessage.Question{Name: name, Type: dnsTypeHTTPS, Class: dnsmessage.ClassINET})
+		b.StartAnswers()
+		for _, a := range answers {
+			if err := a(&b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		msg, err := b.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	https := func(ttl uint32, data []byte) func(b *dnsmessage.Builder) error {
+		return func(b *dnsmessage.Builder) error {
+			return b.UnknownResource(
+				dnsmessage.ResourceHeader{Name: name, Type: dnsTypeHTTPS, Class: dnsmessage.ClassINET, TTL: ttl},
+				dnsmessage.UnknownResource{Type: dnsTypeHTTPS, Data: data},
+			)
+		}
+	}
+	cname := func(b *dnsmessage.Builder) error {
+		return b.CNAMEResource(
+			dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 60},
+			dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("cdn.example.net.")},
+		)
+	}
+
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    []byte
+		wantTTL time.Duration
+		wantErr bool
+	}{
+		{"single record", build(https(300, svcRDATA(1, []byte{0}, svcParam(svcParamKeyECH, echList)))), echList, 300 * time.Second, false},
+		{"skips cname and alias", build(cname, https(60, svcRDATA(0, []byte{0})), https(120, svcRDATA(1, []byte{0}, svcParam(svcParamKeyECH, echList)))), echList, 120 * time.Second, false},
+		{"no ech param", build(https(300, svcRDATA(1, []byte{0}, svcParam(1, []byte{2, 'h', '2'})))), nil, 0, true},
+		{"no answers", build(), nil, 0, true},
+		{"garbage", []byte{1, 2, 3}, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ttl, err := parseHTTPSRecordECH(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) || ttl != tt.wantTTL {
+				t.Errorf("parseHTTPSRecordECH() = (%x, %v), want (%x, %v)", got, ttl, tt.want, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestBuildDNSQuery(t *testing.T) {
+	tests := []struct {
+		host  string
+		qtype dnsmessage.Type
+	}{
+		{"example.com", dnsTypeHTTPS},
+		{"Example.COM.", dnsmessage.TypeA},
+		{"a.b.example.org", dnsmessage.TypeAAAA},
+	}
+	for _, tt := range tests {
+		query, err := buildDNSQuery(dnsmessage.MustNewName(dnsFQDN(tt.host)), tt.qtype)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Response || !h.RecursionDesired {
+			t.Errorf("%s: header = %+v", tt.host, h)
+		}
+		q, err := p.Question()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Name.String() != dnsFQDN(tt.host) || q.Type != tt.qtype || q.Class != dnsmessage.ClassINET {
+			t.Errorf("%s: question = %v", tt.host, q)
+		}
+	}
+}
+
+func TestGetECHConfigListSources(t *testing.T) {
+	// Servidor UDP que só conta as consultas: não pode ser usado com proxy
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, _, err := udp.ReadFrom(buf); err != nil {
+				return
+			}
+			queries.Add(1)
+		}
+	}()
+
+	static := []byte{0x00, 0x01}
+	global := []byte{0x00, 0x02}
+	tests := []struct {
+		name        string
+		config      ClientConfig
+		host        string
+		proxied     bool
+		want        []byte
+		wantQueries bool
+	}{
+		{"per-host config", ClientConfig{ECHConfigs: map[string][]byte{"a.test": static}, ECHConfigList: global}, "a.test", false, static, false},
+		{"global config", ClientConfig{ECHConfigs: map[string][]byte{"a.test": static}, ECHConfigList: global}, "b.test", false, global, false},
+		{"dns disabled", ClientConfig{}, "c.test", false, nil, false},
+		{"ip literal", ClientConfig{ECHFromDNS: true, ECHDNSServer: udp.LocalAddr().String()}, "192.0.2.1", false, nil, false},
+		{"proxied without encrypted dns", ClientConfig{ECHFromDNS: true, ECHDNSServer: udp.LocalAddr().String()}, "d.test", true, nil, false},
+		{"direct uses udp", ClientConfig{ECHFromDNS: true, ECHDNSServer: udp.LocalAddr().String()}, "e.test", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := queries.Load()
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			if got := getECHConfigList(ctx, tt.host, &tt.config, tt.proxied); !bytes.Equal(got, tt.want) {
+				t.Errorf("getECHConfigList() = %x, want %x", got, tt.want)
+			}
+			time.Sleep(20 * time.Millisecond)
+			if sent := queries.Load() > before; sent != tt.wantQueries {
+				t.Errorf("DNS query sent = %v, want %v", sent, tt.wantQueries)
+			}
+		})
+	}
+}
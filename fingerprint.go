@@ -1,167 +1,275 @@
-package browserclient
-
-import (
-	"context"
-	"crypto/tls"
-	"crypto/x509"
-	"fmt"
-	"math/rand"
-	"net"
-	"strings"
-	"time"
-
-	utls "github.com/refraction-networking/utls"
-)
-
-// Mapeamento mais preciso de fingerprints por User-Agent
-var browserFingerprints = map[string][]utls.ClientHelloID{
-	"Chrome": {
-		utls.HelloChrome_Auto,
-		utls.HelloChrome_120,
-	},
-	"Firefox": {
-		utls.HelloFirefox_Auto,
-		utls.HelloFirefox_120,
-	},
-	"Safari": {
-		utls.HelloSafari_Auto,
-		utls.HelloSafari_16_0,
-		utls.HelloIOS_Auto,
-	},
-	"Edge": {
-		utls.HelloEdge_Auto,
-		utls.HelloChrome_120, // Edge usa engine Chromium
-	},
-}
-
-func dialTLS(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile) (net.Conn, error) {
-	// Configurar timeout para o dial
-	dialer := &net.Dialer{
-		Timeout: 10 * time.Second,
-	}
-	
-	rawConn, err := dialer.DialContext(ctx, network, addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
-	}
-
-	host, _, _ := net.SplitHostPort(addr)
-	
-	// Configuração TLS base
-	tlsConfig := &utls.Config{
-		ServerName:         host,
-		InsecureSkipVerify: config.DisableTLSVerify,
-		NextProtos:         getALPNProtocols(profile.UserAgent),
-		MinVersion:         tls.VersionTLS12,
-		MaxVersion:         tls.VersionTLS13,
-	}
-
-	if !config.DisableTLSVerify {
-		tlsConfig.RootCAs = getSystemCertPool()
-	}
-
-	// Selecionar fingerprint baseado no navegador
-	fingerprint := selectFingerprint(profile.UserAgent, config.RandomizeTLS)
-	
-	uConn := utls.UClient(rawConn, tlsConfig, fingerprint)
-	
-	// Aplicar configurações específicas do navegador se necessário
-	if err := applyBrowserSpecificSettings(uConn, profile); err != nil {
-		rawConn.Close()
-		return nil, fmt.Errorf("failed to apply browser settings: %w", err)
-	}
-
-	// Handshake com timeout
-	handshakeCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-
-	errChan := make(chan error, 1)
-	go func() {
-		errChan <- uConn.HandshakeContext(handshakeCtx)
-	}()
-
-	select {
-	case err := <-errChan:
-		if err != nil {
-			rawConn.Close()
-			return nil, fmt.Errorf("TLS handshake failed: %w", err)
-		}
-		return &tlsConn{uConn, profile}, nil
-	case <-handshakeCtx.Done():
-		rawConn.Close()
-		return nil, fmt.Errorf("TLS handshake timeout: %w", handshakeCtx.Err())
-	}
-}
-
-// Wrapper para adicionar informações do perfil à conexão
-type tlsConn struct {
-	*utls.UConn
-	profile *BrowserProfile
-}
-
-func selectFingerprint(userAgent string, randomize bool) utls.ClientHelloID {
-	if randomize {
-		return utls.HelloRandomized
-	}
-
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
-
-	// Identificar o navegador
-	browser := "Chrome" // default
-	for b := range browserFingerprints {
-		if strings.Contains(userAgent, b) {
-			browser = b
-			break
-		}
-	}
-
-	fingerprints := browserFingerprints[browser]
-	return fingerprints[r.Intn(len(fingerprints))]
-}
-
-func getALPNProtocols(userAgent string) []string {
-	// Safari às vezes não anuncia h2
-	if strings.Contains(userAgent, "Safari") && !strings.Contains(userAgent, "Chrome") {
-		if rand.Float32() < 0.3 {
-			return []string{"http/1.1"}
-		}
-	}
-	return []string{"h2", "http/1.1"}
-}
-
-func applyBrowserSpecificSettings(uConn *utls.UConn, profile *BrowserProfile) error {
-	// Para fingerprints específicos, podemos customizar ainda mais
-	// Nota: Com versões recentes do uTLS, a customização é mais limitada
-	// para manter a integridade do fingerprint
-	
-	if strings.Contains(profile.UserAgent, "Firefox") {
-		// Firefox específico já está configurado no ClientHelloID
-		return nil
-	}
-	
-	if strings.Contains(profile.UserAgent, "Chrome") {
-		// Chrome específico já está configurado no ClientHelloID
-		return nil
-	}
-	
-	return nil
-}
-
-func getSystemCertPool() *x509.CertPool {
-	pool, err := x509.SystemCertPool()
-	if err != nil {
-		// Fallback para pool vazio se falhar
-		return x509.NewCertPool()
-	}
-	return pool
-}
-
-// Função auxiliar para debug de TLS (opcional)
-func debugTLSInfo(conn *utls.UConn) {
-	state := conn.ConnectionState()
-	fmt.Printf("TLS Version: %x\n", state.Version)
-	fmt.Printf("Cipher Suite: %x\n", state.CipherSuite)
-	fmt.Printf("Server Name: %s\n", state.ServerName)
-	fmt.Printf("Negotiated Protocol: %s\n", state.NegotiatedProtocol)
-}
\ No newline at end of file
+package browserclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// Mapeamento mais preciso de fingerprints por User-Agent
+var browserFingerprints = map[string][]utls.ClientHelloID{
+	"Firefox": {
+		utls.HelloFirefox_Auto,
+		utls.HelloFirefox_120,
+	},
+	"Safari": {
+		utls.HelloSafari_Auto,
+		utls.HelloSafari_16_0,
+	},
+	// Safari do iPhone: o iOS 16 manda o ClientHello do Safari 16 do macOS
+	// (os presets HelloIOS_* param no iOS 14). Por isso o catálogo móvel só
+	// tem UAs do iOS 16
+	"iOS": {
+		utls.HelloSafari_16_0,
+	},
+	// Chrome, Edge, Opera, Brave e Samsung Internet usam o BoringSSL do
+	// Chromium: o ClientHello é o do Chrome da mesma versão (os presets
+	// HelloEdge_* são do Edge 85/106). A chave é a faixa de versão do
+	// Chromium no UA; veja chromiumFamily
+	"Chrome133": {
+		utls.HelloChrome_133,
+	},
+	"Chrome131": { // X25519MLKEM768 no lugar do Kyber
+		utls.HelloChrome_131,
+	},
+	"Chrome124": { // X25519Kyber768 ligado por padrão no desktop
+		utls.HelloChrome_120_PQ,
+	},
+	"Chrome120": {
+		utls.HelloChrome_120,
+	},
+}
+
+func dialTLS(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile, proxies *ProxyPool, rootCAs *x509.CertPool, echStatus *echStatusStore) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	echConfigList := getECHConfigList(ctx, host, config, proxies != nil)
+
+	conn, err := dialTLSOnce(ctx, network, addr, config, profile, proxies, rootCAs, echConfigList)
+
+	// Servidor rejeitou o ECH mas enviou configs atualizadas: tentar de novo uma vez
+	var echErr *utls.ECHRejectionError
+	if errors.As(err, &echErr) && len(echErr.RetryConfigList) > 0 {
+		key := echCacheKey{resolver: dnsExchangerFor(config), host: host}
+		storeECHConfigList(key, echErr.RetryConfigList, echRetryConfigTTL)
+		conn, err = dialTLSOnce(ctx, network, addr, config, profile, proxies, rootCAs, echErr.RetryConfigList)
+	}
+	if tc, ok := conn.(*tlsConn); ok && tc.echOffered {
+		echStatus.record(host, tc.ECHAccepted())
+	}
+	return conn, err
+}
+
+func dialTLSOnce(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile, proxies *ProxyPool, rootCAs *x509.CertPool, echConfigList []byte) (net.Conn, error) {
+	// Com proxy, o handshake uTLS corre dentro do túnel e mantém o fingerprint
+	rawConn, timing, proxyName, err := dialThroughPool(ctx, network, addr, config, profile, proxies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	
+	// Configuração TLS base
+	tlsConfig := &utls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: config.DisableTLSVerify,
+		NextProtos:         getALPNProtocols(profile.UserAgent),
+		MinVersion:         tls.VersionTLS12,
+		MaxVersion:         tls.VersionTLS13,
+	}
+
+	if !config.DisableTLSVerify {
+		tlsConfig.RootCAs = rootCAs
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = getSystemCertPool()
+		}
+	}
+
+	// Pinning SPKI e callback de verificação (fora do ClientHello)
+	tlsConfig.VerifyConnection = buildVerifyConnection(host, config)
+
+	// mTLS: o certificado só é enviado se o servidor pedir
+	tlsConfig.GetClientCertificate = buildGetClientCertificate(host, config)
+
+	// Cache de sessões por perfil para resumption como um navegador recorrente
+	var sessionCache *tlsSessionCache
+	if !config.DisableSessionResumption {
+		sessionCache = getSessionCache(profile, config)
+		tlsConfig.ClientSessionCache = sessionCache
+		tlsConfig.PreferSkipResumptionOnNilExtension = true
+		tlsConfig.OmitEmptyPsk = true
+	}
+
+	// Selecionar fingerprint baseado no navegador
+	fingerprint := selectFingerprint(fingerprintFamily(profile), config.RandomizeTLS)
+
+	// ECH real só é possível se o preset do navegador já envia a extensão
+	// (GREASE). Precisa estar no config antes do UClient/ApplyPreset: é ali
+	// que o uTLS troca o SNI externo pelo public name, inclusive na resumption.
+	if len(echConfigList) > 0 && fingerprintSupportsECH(fingerprint) {
+		tlsConfig.EncryptedClientHelloConfigList = echConfigList
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	uConn := utls.UClient(rawConn, tlsConfig, fingerprint)
+	var customSpec *utls.ClientHelloSpec
+
+	// Com sessão salva, o navegador inclui pre_shared_key no ClientHello
+	if sessionCache != nil && sessionCache.has(host) {
+		if spec := resumableSpec(fingerprint); spec != nil {
+			customSpec = spec
+			uConn = utls.UClient(rawConn, tlsConfig, utls.HelloCustom)
+			if err := uConn.ApplyPreset(spec); err != nil {
+				rawConn.Close()
+				return nil, fmt.Errorf("failed to apply resumption preset: %w", err)
+			}
+		}
+	}
+	
+	// Aplicar configurações específicas do navegador se necessário
+	if err := applyBrowserSpecificSettings(uConn, profile); err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("failed to apply browser settings: %w", err)
+	}
+
+	// Handshake com timeout
+	handshakeStart := time.Now()
+	handshakeCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- uConn.HandshakeContext(handshakeCtx)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			rawConn.Close()
+			return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		timing.TLSHandshake = time.Since(handshakeStart)
+		return &tlsConn{
+			UConn:      uConn,
+			profile:    profile,
+			helloID:    fingerprint,
+			helloSpec:  customSpec,
+			timing:     timing,
+			proxy:      proxyName,
+			echOffered: len(tlsConfig.EncryptedClientHelloConfigList) > 0,
+		}, nil
+	case <-handshakeCtx.Done():
+		rawConn.Close()
+		return nil, fmt.Errorf("TLS handshake timeout: %w", handshakeCtx.Err())
+	}
+}
+
+// Wrapper para adicionar informações do perfil à conexão
+type tlsConn struct {
+	*utls.UConn
+	profile   *BrowserProfile
+	helloID   utls.ClientHelloID
+	helloSpec *utls.ClientHelloSpec
+	timing    connTiming
+	proxy     string
+	// Foi oferecido ECH real (não só GREASE)
+	echOffered bool
+}
+
+// ECHAccepted indica se o servidor aceitou o Encrypted Client Hello
+func (c *tlsConn) ECHAccepted() bool {
+	return c.ConnectionState().ECHAccepted
+}
+
+// fingerprintFamily é a chave de browserFingerprints do perfil. No celular o
+// preset acompanha a versão do UA, que fica atrás da do desktop.
+func fingerprintFamily(profile *BrowserProfile) string {
+	browser := profileBrowser(profile)
+	switch uaPlatform(profile.UserAgent) {
+	case "iOS":
+		if browser == "Safari" {
+			return "iOS"
+		}
+	}
+	switch browser {
+	case "Firefox", "Safari":
+		return browser
+	}
+	return chromiumFamily(profile.UserAgent)
+}
+
+// chromiumFamily escolhe o preset pela versão do Chromium no UA, e não pela
+// marca: Edge 125 e Chrome 125 mandam o mesmo ClientHello. O Kyber do 124 ao
+// 130 só veio ligado no desktop; no Android essa faixa fica no Chrome 120
+func chromiumFamily(userAgent string) string {
+	major, err := strconv.Atoi(chromeMajorVersion(userAgent))
+	switch {
+	case err != nil, major >= 133:
+		return "Chrome133"
+	case major >= 131:
+		return "Chrome131"
+	case major >= 124 && uaPlatform(userAgent) != "Android":
+		return "Chrome124"
+	}
+	return "Chrome120"
+}
+
+func selectFingerprint(browser string, randomize bool) utls.ClientHelloID {
+	if randomize {
+		return utls.HelloRandomized
+	}
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	fingerprints, ok := browserFingerprints[browser]
+	if !ok {
+		fingerprints = browserFingerprints["Chrome133"]
+	}
+	return fingerprints[r.Intn(len(fingerprints))]
+}
+
+func getALPNProtocols(userAgent string) []string {
+	// Safari às vezes não anuncia h2
+	if strings.Contains(userAgent, "Safari") && !strings.Contains(userAgent, "Chrome") {
+		if rand.Float32() < 0.3 {
+			return []string{"http/1.1"}
+		}
+	}
+	return []string{"h2", "http/1.1"}
+}
+
+func applyBrowserSpecificSettings(uConn *utls.UConn, profile *BrowserProfile) error {
+	// Para fingerprints específicos, podemos customizar ainda mais
+	// Nota: Com versões recentes do uTLS, a customização é mais limitada
+	// para manter a integridade do fingerprint
+	
+	if strings.Contains(profile.UserAgent, "Firefox") {
+		// Firefox específico já está configurado no ClientHelloID
+		return nil
+	}
+	
+	if strings.Contains(profile.UserAgent, "Chrome") {
+		// Chrome específico já está configurado no ClientHelloID
+		return nil
+	}
+	
+	return nil
+}
+
+func getSystemCertPool() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		// Fallback para pool vazio se falhar
+		return x509.NewCertPool()
+	}
+	return pool
+}
@@ -0,0 +1,101 @@
+package browserclient
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+func TestFingerprintFamily(t *testing.T) {
+	tests := []struct {
+		name    string
+		browser string
+		ua      string
+		want    string
+	}{
+		{"chrome 123", "Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36", "Chrome120"},
+		{"chrome 125", "Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36", "Chrome124"},
+		{"chrome 131", "Chrome", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36", "Chrome131"},
+		{"chrome 135", "Chrome", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36", "Chrome133"},
+		{"edge follows chromium", "Edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Chrome124"},
+		{"opera follows chromium", "Opera", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36 OPR/117.0.0.0", "Chrome131"},
+		{"brave follows chromium", "Brave", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36", "Chrome120"},
+		{"android chrome 126", "Chrome", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36", "Chrome120"},
+		{"android chrome 131", "Chrome", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Mobile Safari/537.36", "Chrome131"},
+		{"android chrome 133", "Chrome", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Mobile Safari/537.36", "Chrome133"},
+		{"samsung", "Samsung", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/26.0 Chrome/122.0.0.0 Mobile Safari/537.36", "Chrome120"},
+		{"firefox android", "Firefox", "Mozilla/5.0 (Android 14; Mobile; rv:126.0) Gecko/126.0 Firefox/126.0", "Firefox"},
+		{"ios safari", "Safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1", "iOS"},
+		{"firefox", "Firefox", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:126.0) Gecko/20100101 Firefox/126.0", "Firefox"},
+		{"safari", "Safari", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15", "Safari"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &BrowserProfile{Browser: tt.browser, UserAgent: tt.ua}
+			if got := fingerprintFamily(profile); got != tt.want {
+				t.Errorf("fingerprintFamily() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Todo UA do catálogo precisa cair numa família com preset
+func TestCatalogFingerprints(t *testing.T) {
+	// No iPhone o preset (Safari 16) só vale para o iOS 16
+	for _, agent := range mobileUserAgents {
+		if uaPlatform(agent.userAgent) == "iOS" && !strings.Contains(agent.userAgent, "iPhone OS 16_") {
+			t.Errorf("%s: iOS version without a matching preset", agent.userAgent)
+		}
+		// Android 124-130 não tem preset exato (Kyber só no desktop)
+		if uaPlatform(agent.userAgent) == "Android" {
+			if major, _ := strconv.Atoi(chromeMajorVersion(agent.userAgent)); major >= 124 && major < 131 {
+				t.Errorf("%s: Android Chromium %d has no matching preset", agent.userAgent, major)
+			}
+		}
+	}
+
+	for _, catalog := range [][]catalogAgent{userAgents, mobileUserAgents} {
+		for _, agent := range catalog {
+			profile := &BrowserProfile{Browser: agent.browser, UserAgent: agent.userAgent}
+			family := fingerprintFamily(profile)
+			presets, ok := browserFingerprints[family]
+			if !ok {
+				t.Errorf("%s: family %q has no presets", agent.userAgent, family)
+				continue
+			}
+			for _, id := range presets {
+				if _, err := utls.UTLSIdToSpec(id); err != nil {
+					t.Errorf("%s: preset %s: %v", agent.userAgent, id.Str(), err)
+				}
+			}
+		}
+	}
+}
+
+func TestNavigatorPlatform(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36", "Win32"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:126.0) Gecko/20100101 Firefox/126.0", "MacIntel"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36", "Linux x86_64"},
+	}
+	for _, tt := range tests {
+		if got := navigatorPlatform(tt.ua); got != tt.want {
+			t.Errorf("navigatorPlatform(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+
+	for seed := int64(1); seed <= 50; seed++ {
+		profile, err := GenerateProfile("", seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := navigatorPlatform(profile.UserAgent); profile.Platform != want {
+			t.Errorf("seed %d: Platform %q for UA %q, want %q", seed, profile.Platform, profile.UserAgent, want)
+		}
+	}
+}
@@ -5,13 +5,15 @@ go 1.24
 toolchain go1.24.5
 
 require (
+	github.com/andybalholm/brotli v1.0.6
+	github.com/klauspost/compress v1.17.4
 	github.com/refraction-networking/utls v1.8.0
+	golang.org/x/crypto v0.40.0
 	golang.org/x/net v0.42.0
+	software.sslmate.com/src/go-pkcs12 v0.7.3
 )
 
 require (
-	github.com/andybalholm/brotli v1.0.6 // indirect
-	github.com/klauspost/compress v1.17.4 // indirect
-	golang.org/x/crypto v0.40.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
+	golang.org/x/text v0.27.0 // indirect
 )
@@ -0,0 +1,666 @@
+package browserclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+const (
+	defaultHARBodySize = 64 << 10
+	harRedacted        = "[REDACTED]"
+	harVersion         = "1.2"
+)
+
+// DefaultRedactedHeaders são os headers mascarados por padrão no HAR. Um HAR
+// gravado assim não reproduz login nem sessão no HARReplayer, que recusa
+// Set-Cookie mascarado: para gravações de replay, tire "Set-Cookie" de
+// RedactHeaders (o arquivo passa a conter os cookies de sessão).
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HAR é um arquivo HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/)
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+
+	// Campos próprios (prefixo "_", permitido pela especificação)
+	TLS       *HARTLS `json:"_tls,omitempty"`
+	Proxy     string  `json:"_proxy,omitempty"`
+	FromCache string  `json:"_fromCache,omitempty"`
+	Error     string  `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	SameSite string     `json:"sameSite,omitempty"`
+}
+
+type HARPostData struct {
+	MimeType  string `json:"mimeType"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"_truncated,omitempty"`
+}
+
+type HARContent struct {
+	Size        int64  `json:"size"`
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Truncated   bool   `json:"_truncated,omitempty"`
+}
+
+// HARTimings em milissegundos; -1 indica fase que não ocorreu
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+type HARTLS struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ALPN        string `json:"alpn,omitempty"`
+	Resumed     bool   `json:"resumed,omitempty"`
+	ECHAccepted bool   `json:"echAccepted,omitempty"`
+	ClientHello string `json:"clientHello,omitempty"`
+}
+
+// HARRecorder grava em HAR todas as idas ao servidor (Do, StreamGet e cada
+// redirect). Os headers de requisição ficam na ordem em que foram escritos
+// na conexão; os de resposta, em ordem alfabética. Pode ser compartilhado
+// entre vários BrowserClient.
+type HARRecorder struct {
+	// Grava os corpos de requisição e resposta (já decodificados)
+	IncludeBodies bool
+	// Corpos maiores são truncados (padrão: 64 KB)
+	MaxBodySize int64
+	// Headers cujo valor é mascarado (sem distinção de maiúsculas)
+	RedactHeaders []string
+
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+// NewHARRecorder cria um gravador que mascara DefaultRedactedHeaders (veja a
+// ressalva sobre replay lá)
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{
+		RedactHeaders: append([]string(nil), DefaultRedactedHeaders...),
+	}
+}
+
+// HAR retorna uma cópia do que foi gravado até agora
+func (r *HARRecorder) HAR() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*HAREntry, len(r.entries))
+	for i, e := range r.entries {
+		copied := *e
+		entries[i] = &copied
+	}
+	return &HAR{Log: HARLog{
+		Version: harVersion,
+		Creator: HARCreator{Name: "browserclient", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// WriteTo escreve o HAR em JSON
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile salva o HAR em path
+func (r *HARRecorder) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// Reset descarta as entradas gravadas
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+func (r *HARRecorder) add(entry *HAREntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+func (r *HARRecorder) maxBodySize() int64 {
+	if r.MaxBodySize > 0 {
+		return r.MaxBodySize
+	}
+	return defaultHARBodySize
+}
+
+func (r *HARRecorder) redacted(name string) bool {
+	for _, h := range r.RedactHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// harTrace acompanha uma ida ao servidor via httptrace
+type harTrace struct {
+	mu           sync.Mutex
+	headers      []HARNameValue
+	conn         *ConnectionInfo
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+}
+
+func (ht *harTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			ci := newConnectionInfo(info.Conn)
+			ci.Reused = info.Reused
+			ht.mu.Lock()
+			ht.conn = ci
+			ht.gotConn = time.Now()
+			// Uma nova conexão (ex.: retry do transport) recomeça a captura
+			ht.headers = nil
+			ht.mu.Unlock()
+		},
+		WroteHeaderField: func(key string, values []string) {
+			ht.mu.Lock()
+			for _, v := range values {
+				ht.headers = append(ht.headers, HARNameValue{Name: key, Value: v})
+			}
+			ht.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			ht.mu.Lock()
+			ht.wroteRequest = time.Now()
+			ht.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			ht.mu.Lock()
+			ht.firstByte = time.Now()
+			ht.mu.Unlock()
+		},
+	}
+}
+
+// harTransport registra cada RoundTrip no HARRecorder
+type harTransport struct {
+	next     http.RoundTripper
+	recorder *HARRecorder
+	// Origem dos acertos de cache ("memory" ou "disk")
+	cacheKind string
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ht := &harTrace{}
+	started := time.Now()
+	entry := &HAREntry{StartedDateTime: started}
+
+	postData, reqBodySize := t.capturePostData(req)
+
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), ht.clientTrace()))
+	resp, err := t.next.RoundTrip(traced)
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	entry.Request = t.harRequest(req, ht.headers)
+	entry.Request.PostData = postData
+	entry.Request.BodySize = reqBodySize
+
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HARResponse{Cookies: []HARCookie{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1}
+		entry.Timings = harTimings(started, ht, time.Now())
+		entry.Time = harTotal(entry.Timings)
+		t.recorder.add(entry)
+		return nil, err
+	}
+
+	fromCache := resp.Header.Get(CacheHeader) != ""
+	if fromCache {
+		entry.FromCache = t.cacheKind
+	}
+	if ci := ht.conn; ci != nil {
+		if ci.RemoteIP != nil {
+			entry.ServerIPAddress = ci.RemoteIP.String()
+		}
+		if _, port, err := net.SplitHostPort(ci.LocalAddr); err == nil {
+			entry.Connection = port
+		}
+		entry.Proxy = ci.Proxy
+		if ci.TLSVersion != 0 {
+			entry.TLS = &HARTLS{
+				Version:     utls.VersionName(ci.TLSVersion),
+				CipherSuite: utls.CipherSuiteName(ci.CipherSuite),
+				ALPN:        ci.ALPN,
+				Resumed:     ci.DidResume,
+				ECHAccepted: ci.ECHAccepted,
+				ClientHello: ci.ClientHelloID.Str(),
+			}
+		}
+	}
+	version := harHTTPVersion(resp.Proto)
+	entry.Request.HTTPVersion = version
+	if version != "HTTP/1.1" && version != "HTTP/1.0" {
+		entry.Request.HeadersSize = -1
+	}
+	entry.Response = t.harResponse(resp, version)
+	t.recorder.add(entry)
+
+	// Tempos e corpo são concluídos quando a resposta termina de ser lida
+	finish := func(raw []byte, size int64, truncated bool) {
+		end := time.Now()
+		ht.mu.Lock()
+		timings := harTimings(started, ht, end)
+		ht.mu.Unlock()
+
+		t.recorder.mu.Lock()
+		defer t.recorder.mu.Unlock()
+		entry.Timings = timings
+		entry.Time = harTotal(timings)
+		if fromCache {
+			entry.Response.BodySize = 0
+		} else {
+			entry.Response.BodySize = size
+		}
+		t.fillContent(&entry.Response.Content, resp.Header, raw, size, truncated)
+	}
+	resp.Body = &harBody{ReadCloser: resp.Body, limit: t.recorder.maxBodySize(), keep: t.recorder.IncludeBodies, finish: finish}
+	return resp, nil
+}
+
+func (t *harTransport) CloseIdleConnections() {
+	if transport, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+// capturePostData lê o corpo pela cópia do GetBody, sem consumir o original
+func (t *harTransport) capturePostData(req *http.Request) (*HARPostData, int64) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, 0
+	}
+	size := req.ContentLength
+	postData := &HARPostData{MimeType: req.Header.Get("Content-Type")}
+	if !t.recorder.IncludeBodies || req.GetBody == nil {
+		return postData, size
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return postData, size
+	}
+	defer body.Close()
+
+	limit := t.recorder.maxBodySize()
+	data, _ := io.ReadAll(io.LimitReader(body, limit+1))
+	if int64(len(data)) > limit {
+		data = data[:limit]
+		postData.Truncated = true
+	}
+	postData.Text = string(data)
+	return postData, size
+}
+
+func (t *harTransport) harRequest(req *http.Request, wire []HARNameValue) HARRequest {
+	headers := wire
+	if len(headers) == 0 {
+		// Sem escrita na conexão (cache ou erro): usa o mapa de headers
+		headers = []HARNameValue{{Name: "Host", Value: req.Host}}
+		if req.Host == "" {
+			headers[0].Value = req.URL.Host
+		}
+		headers = append(headers, sortedHeaders(req.Header)...)
+	}
+
+	hr := HARRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARCookie{},
+		QueryString: []HARNameValue{},
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			hr.QueryString = append(hr.QueryString, HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(hr.QueryString, func(i, j int) bool { return hr.QueryString[i].Name < hr.QueryString[j].Name })
+
+	hr.Headers = make([]HARNameValue, 0, len(headers))
+	size := int64(len(req.Method) + len(req.URL.RequestURI()) + len(" HTTP/1.1\r\n") + 2)
+	for _, h := range headers {
+		redact := t.recorder.redacted(h.Name)
+		if strings.EqualFold(h.Name, "Cookie") {
+			for _, c := range (&http.Request{Header: http.Header{"Cookie": {h.Value}}}).Cookies() {
+				value := c.Value
+				if redact {
+					value = harRedacted
+				}
+				hr.Cookies = append(hr.Cookies, HARCookie{Name: c.Name, Value: value})
+			}
+		}
+		value := h.Value
+		if redact {
+			value = harRedacted
+		}
+		hr.Headers = append(hr.Headers, HARNameValue{Name: h.Name, Value: value})
+		size += int64(len(h.Name) + len(h.Value) + 4)
+	}
+	hr.HeadersSize = size
+	return hr
+}
+
+func (t *harTransport) harResponse(resp *http.Response, version string) HARResponse {
+	hr := HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: version,
+		Cookies:     []HARCookie{},
+		Headers:     []HARNameValue{},
+		Content:     HARContent{MimeType: resp.Header.Get("Content-Type")},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	redactCookies := t.recorder.redacted("Set-Cookie")
+	for _, c := range resp.Cookies() {
+		hc := HARCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if redactCookies {
+			hc.Value = harRedacted
+		}
+		if !c.Expires.IsZero() {
+			expires := c.Expires
+			hc.Expires = &expires
+		}
+		switch c.SameSite {
+		case http.SameSiteLaxMode:
+			hc.SameSite = "Lax"
+		case http.SameSiteStrictMode:
+			hc.SameSite = "Strict"
+		case http.SameSiteNoneMode:
+			hc.SameSite = "None"
+		}
+		hr.Cookies = append(hr.Cookies, hc)
+	}
+
+	size := int64(len(resp.Proto) + len(resp.Status) + 4)
+	for _, h := range sortedHeaders(resp.Header) {
+		if h.Name == CacheHeader {
+			continue
+		}
+		size += int64(len(h.Name) + len(h.Value) + 4)
+		if t.recorder.redacted(h.Name) {
+			h.Value = harRedacted
+		}
+		hr.Headers = append(hr.Headers, h)
+	}
+	if version == "HTTP/1.1" || version == "HTTP/1.0" {
+		hr.HeadersSize = size + 2
+	}
+
+	if loc := resp.Header.Get("Location"); loc != "" {
+		if u, err := resp.Request.URL.Parse(loc); err == nil {
+			hr.RedirectURL = u.String()
+		} else {
+			hr.RedirectURL = loc
+		}
+	}
+	return hr
+}
+
+// fillContent decodifica o corpo capturado para o campo content do HAR
+func (t *harTransport) fillContent(content *HARContent, header http.Header, raw []byte, size int64, truncated bool) {
+	content.Size = size
+	content.Truncated = truncated
+	if !t.recorder.IncludeBodies {
+		return
+	}
+
+	body := raw
+	switch encoding := strings.ToLower(header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case "gzip", "deflate", "br", "zstd":
+		reader, err := getResponseReader(&http.Response{Header: header, Body: io.NopCloser(bytes.NewReader(raw))})
+		if err != nil {
+			return
+		}
+		// Corpo truncado decodifica até onde der
+		decoded, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil && len(decoded) == 0 {
+			return
+		}
+		body = decoded
+		if !truncated {
+			content.Size = int64(len(decoded))
+			content.Compression = content.Size - size
+		}
+	default:
+		// Codificação desconhecida: o texto fica de fora
+		return
+	}
+
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+}
+
+// harBody conta (e opcionalmente copia) o corpo até o fim ou o Close
+type harBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	size      int64
+	limit     int64
+	keep      bool
+	truncated bool
+	once      sync.Once
+	finish    func(raw []byte, size int64, truncated bool)
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.keep && n > 0 {
+		room := b.limit - int64(b.buf.Len())
+		if int64(n) > room {
+			b.truncated = true
+			if room > 0 {
+				b.buf.Write(p[:room])
+			}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *harBody) done() {
+	b.once.Do(func() {
+		b.finish(b.buf.Bytes(), b.size, b.truncated)
+	})
+}
+
+// harTimings divide o tempo da ida ao servidor nas fases do HAR
+func harTimings(started time.Time, ht *harTrace, end time.Time) HARTimings {
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, Send: -1, Wait: -1, Receive: -1, SSL: -1}
+	if ht.gotConn.IsZero() {
+		return timings
+	}
+
+	blocked := ht.gotConn.Sub(started)
+	if ci := ht.conn; ci != nil && !ci.Reused {
+		timings.DNS = harMillis(ci.Timing.DNS)
+		timings.Connect = harMillis(ci.Timing.Connect + ci.Timing.TLSHandshake)
+		if ci.TLSVersion != 0 {
+			timings.SSL = harMillis(ci.Timing.TLSHandshake)
+		}
+		blocked -= ci.Timing.DNS + ci.Timing.Connect + ci.Timing.TLSHandshake
+	}
+	if blocked < 0 {
+		blocked = 0
+	}
+	timings.Blocked = harMillis(blocked)
+
+	if !ht.wroteRequest.IsZero() {
+		timings.Send = harMillis(ht.wroteRequest.Sub(ht.gotConn))
+		if !ht.firstByte.IsZero() {
+			timings.Wait = harMillis(ht.firstByte.Sub(ht.wroteRequest))
+			timings.Receive = harMillis(end.Sub(ht.firstByte))
+		}
+	}
+	return timings
+}
+
+// harTotal soma as fases (ssl já está incluído em connect)
+func harTotal(t HARTimings) float64 {
+	total := 0.0
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+func harMillis(d time.Duration) float64 {
+	if d < 0 {
+		d = 0
+	}
+	return float64(d.Microseconds()) / 1000
+}
+
+// harHTTPVersion segue a grafia do DevTools do Chrome
+func harHTTPVersion(proto string) string {
+	switch proto {
+	case "HTTP/2.0":
+		return "http/2.0"
+	case "HTTP/3.0":
+		return "http/3.0"
+	case "":
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func sortedHeaders(header http.Header) []HARNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := make([]HARNameValue, 0, len(names))
+	for _, name := range names {
+		for _, v := range header[name] {
+			headers = append(headers, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return headers
+}
@@ -0,0 +1,286 @@
+package browserclient
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Headers cujo valor diferente costuma denunciar o cliente
+var criticalDiffHeaders = map[string]bool{
+	"user-agent":                true,
+	"accept":                    true,
+	"accept-encoding":           true,
+	"accept-language":           true,
+	"sec-ch-ua":                 true,
+	"sec-ch-ua-mobile":          true,
+	"sec-ch-ua-platform":        true,
+	"sec-fetch-site":            true,
+	"sec-fetch-mode":            true,
+	"sec-fetch-dest":            true,
+	"sec-fetch-user":            true,
+	"upgrade-insecure-requests": true,
+}
+
+// Headers cujo valor muda a cada sessão e não é comparado
+var volatileDiffHeaders = map[string]bool{
+	"cookie":         true,
+	"content-length": true,
+}
+
+// HARDiffReport compara as requisições de um navegador real com as nossas
+type HARDiffReport struct {
+	Requests []HARRequestDiff
+	// Requisições que só um dos lados fez
+	OnlyBrowser []string
+	OnlyOurs    []string
+}
+
+// HARRequestDiff são as diferenças de uma requisição pareada por método e URL
+type HARRequestDiff struct {
+	Method string
+	URL    string
+
+	BrowserHTTPVersion string
+	OurHTTPVersion     string
+
+	// Headers que o navegador envia e nós não, e vice-versa
+	Missing []string
+	Extra   []string
+	// Mesmo header com grafia diferente
+	Casing []HARHeaderCasing
+	// Ordem dos headers em comum, quando difere
+	BrowserOrder []string
+	OurOrder     []string
+	// Valores diferentes (Critical marca os que o HeaderBuilder gera)
+	Values []HARValueDiff
+
+	// Nomes de cookies enviados só por um dos lados
+	MissingCookies []string
+	ExtraCookies   []string
+}
+
+type HARHeaderCasing struct {
+	Browser string
+	Ours    string
+}
+
+type HARValueDiff struct {
+	Name     string
+	Browser  string
+	Ours     string
+	Critical bool
+}
+
+// Empty indica que a requisição é idêntica à do navegador
+func (d *HARRequestDiff) Empty() bool {
+	return d.BrowserHTTPVersion == d.OurHTTPVersion && len(d.Missing) == 0 && len(d.Extra) == 0 &&
+		len(d.Casing) == 0 && len(d.BrowserOrder) == 0 && len(d.Values) == 0 &&
+		len(d.MissingCookies) == 0 && len(d.ExtraCookies) == 0
+}
+
+// DiffHAR pareia as requisições dos dois HAR por método e URL (na ordem em
+// que aparecem) e compara headers, cookies e versão HTTP
+func DiffHAR(browser, ours *HAR) *HARDiffReport {
+	report := &HARDiffReport{}
+
+	pending := make(map[string][]*HAREntry)
+	for _, e := range ours.Log.Entries {
+		key, err := replayKey(e.Request.Method, e.Request.URL)
+		if err != nil {
+			continue
+		}
+		pending[key] = append(pending[key], e)
+	}
+
+	for _, b := range browser.Log.Entries {
+		key, err := replayKey(b.Request.Method, b.Request.URL)
+		if err != nil {
+			continue
+		}
+		candidates := pending[key]
+		if len(candidates) == 0 {
+			report.OnlyBrowser = append(report.OnlyBrowser, key)
+			continue
+		}
+		pending[key] = candidates[1:]
+		report.Requests = append(report.Requests, DiffHARRequest(b.Request, candidates[0].Request))
+	}
+
+	for key, rest := range pending {
+		for range rest {
+			report.OnlyOurs = append(report.OnlyOurs, key)
+		}
+	}
+	sort.Strings(report.OnlyOurs)
+	return report
+}
+
+// DiffHARRequest compara uma requisição do navegador com a nossa
+func DiffHARRequest(browser, ours HARRequest) HARRequestDiff {
+	d := HARRequestDiff{
+		Method:             browser.Method,
+		URL:                browser.URL,
+		BrowserHTTPVersion: strings.ToLower(browser.HTTPVersion),
+		OurHTTPVersion:     strings.ToLower(ours.HTTPVersion),
+	}
+
+	// Entre HTTP/1.1 e h2, Host/pseudo-headers e grafia diferem por protocolo;
+	// a diferença de versão já é reportada
+	sameProtocol := d.BrowserHTTPVersion == d.OurHTTPVersion
+	protocolHeader := func(lower string) bool {
+		return !sameProtocol && (lower == "host" || strings.HasPrefix(lower, ":"))
+	}
+
+	browserNames, browserValues := indexHARHeaders(browser.Headers)
+	ourNames, ourValues := indexHARHeaders(ours.Headers)
+
+	var browserCommon, ourCommon []string
+	for _, lower := range browserNames.order {
+		if protocolHeader(lower) {
+			continue
+		}
+		if _, ok := ourNames.spelling[lower]; !ok {
+			d.Missing = append(d.Missing, browserNames.spelling[lower])
+			continue
+		}
+		browserCommon = append(browserCommon, lower)
+
+		if b, o := browserNames.spelling[lower], ourNames.spelling[lower]; sameProtocol && b != o {
+			d.Casing = append(d.Casing, HARHeaderCasing{Browser: b, Ours: o})
+		}
+
+		if volatileDiffHeaders[lower] || strings.HasPrefix(lower, ":") {
+			continue
+		}
+		b, o := browserValues[lower], ourValues[lower]
+		if b != o && b != harRedacted && o != harRedacted {
+			d.Values = append(d.Values, HARValueDiff{
+				Name:     browserNames.spelling[lower],
+				Browser:  b,
+				Ours:     o,
+				Critical: criticalDiffHeaders[lower],
+			})
+		}
+	}
+	for _, lower := range ourNames.order {
+		if protocolHeader(lower) {
+			continue
+		}
+		if _, ok := browserNames.spelling[lower]; !ok {
+			d.Extra = append(d.Extra, ourNames.spelling[lower])
+			continue
+		}
+		ourCommon = append(ourCommon, lower)
+	}
+
+	if strings.Join(browserCommon, "\n") != strings.Join(ourCommon, "\n") {
+		d.BrowserOrder = browserCommon
+		d.OurOrder = ourCommon
+	}
+
+	browserCookies := harCookieNames(browser.Cookies)
+	ourCookies := harCookieNames(ours.Cookies)
+	for _, name := range sortedKeys(browserCookies) {
+		if !ourCookies[name] {
+			d.MissingCookies = append(d.MissingCookies, name)
+		}
+	}
+	for _, name := range sortedKeys(ourCookies) {
+		if !browserCookies[name] {
+			d.ExtraCookies = append(d.ExtraCookies, name)
+		}
+	}
+	return d
+}
+
+type harHeaderNames struct {
+	order    []string
+	spelling map[string]string
+}
+
+// indexHARHeaders indexa os headers pelo nome minúsculo, mantendo a ordem
+// da primeira ocorrência; valores repetidos são unidos com ", "
+func indexHARHeaders(headers []HARNameValue) (harHeaderNames, map[string]string) {
+	names := harHeaderNames{spelling: make(map[string]string)}
+	values := make(map[string]string)
+	for _, h := range headers {
+		lower := strings.ToLower(h.Name)
+		if _, ok := names.spelling[lower]; !ok {
+			names.order = append(names.order, lower)
+			names.spelling[lower] = h.Name
+			values[lower] = h.Value
+			continue
+		}
+		values[lower] += ", " + h.Value
+	}
+	return names, values
+}
+
+func harCookieNames(cookies []HARCookie) map[string]bool {
+	names := make(map[string]bool, len(cookies))
+	for _, c := range cookies {
+		names[c.Name] = true
+	}
+	return names
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteTo escreve o relatório em texto; "!" marca os valores críticos
+func (r *HARDiffReport) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	for _, d := range r.Requests {
+		if d.Empty() {
+			fmt.Fprintf(&b, "= %s %s\n", d.Method, d.URL)
+			continue
+		}
+		fmt.Fprintf(&b, "~ %s %s\n", d.Method, d.URL)
+		if d.BrowserHTTPVersion != d.OurHTTPVersion {
+			fmt.Fprintf(&b, "    http version: browser=%s ours=%s\n", d.BrowserHTTPVersion, d.OurHTTPVersion)
+		}
+		for _, h := range d.Missing {
+			fmt.Fprintf(&b, "    - missing header %s\n", h)
+		}
+		for _, h := range d.Extra {
+			fmt.Fprintf(&b, "    + extra header %s\n", h)
+		}
+		for _, c := range d.Casing {
+			fmt.Fprintf(&b, "    casing: browser=%s ours=%s\n", c.Browser, c.Ours)
+		}
+		for _, v := range d.Values {
+			mark := " "
+			if v.Critical {
+				mark = "!"
+			}
+			fmt.Fprintf(&b, "  %s %s:\n        browser: %s\n        ours:    %s\n", mark, v.Name, v.Browser, v.Ours)
+		}
+		if len(d.BrowserOrder) > 0 {
+			fmt.Fprintf(&b, "    order:\n        browser: %s\n        ours:    %s\n", strings.Join(d.BrowserOrder, ", "), strings.Join(d.OurOrder, ", "))
+		}
+		for _, c := range d.MissingCookies {
+			fmt.Fprintf(&b, "    - missing cookie %s\n", c)
+		}
+		for _, c := range d.ExtraCookies {
+			fmt.Fprintf(&b, "    + extra cookie %s\n", c)
+		}
+	}
+	for _, key := range r.OnlyBrowser {
+		fmt.Fprintf(&b, "< only in browser: %s\n", key)
+	}
+	for _, key := range r.OnlyOurs {
+		fmt.Fprintf(&b, "> only in ours: %s\n", key)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
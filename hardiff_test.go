@@ -0,0 +1,132 @@
+package browserclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func harHeaders(pairs ...string) []HARNameValue {
+	headers := make([]HARNameValue, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		headers = append(headers, HARNameValue{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return headers
+}
+
+func TestDiffHARRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		browser HARRequest
+		ours    HARRequest
+		want    HARRequestDiff
+	}{
+		{
+			"identical",
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("user-agent", "UA", "accept", "*/*")},
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("user-agent", "UA", "accept", "*/*")},
+			HARRequestDiff{BrowserHTTPVersion: "h2", OurHTTPVersion: "h2"},
+		},
+		{
+			"missing, extra and critical value",
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("user-agent", "UA1", "sec-ch-ua", `"A"`, "priority", "u=0, i")},
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("user-agent", "UA2", "x-extra", "1")},
+			HARRequestDiff{
+				BrowserHTTPVersion: "h2", OurHTTPVersion: "h2",
+				Missing: []string{"sec-ch-ua", "priority"},
+				Extra:   []string{"x-extra"},
+				Values:  []HARValueDiff{{Name: "user-agent", Browser: "UA1", Ours: "UA2", Critical: true}},
+			},
+		},
+		{
+			"order and casing",
+			HARRequest{HTTPVersion: "HTTP/1.1", Headers: harHeaders("Host", "a", "User-Agent", "UA", "Accept", "*/*")},
+			HARRequest{HTTPVersion: "http/1.1", Headers: harHeaders("Host", "a", "Accept", "*/*", "user-agent", "UA")},
+			HARRequestDiff{
+				BrowserHTTPVersion: "http/1.1", OurHTTPVersion: "http/1.1",
+				Casing:       []HARHeaderCasing{{Browser: "User-Agent", Ours: "user-agent"}},
+				BrowserOrder: []string{"host", "user-agent", "accept"},
+				OurOrder:     []string{"host", "accept", "user-agent"},
+			},
+		},
+		{
+			"protocol headers ignored across versions",
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders(":authority", "a", "accept", "*/*")},
+			HARRequest{HTTPVersion: "HTTP/1.1", Headers: harHeaders("Host", "a", "Accept", "*/*")},
+			HARRequestDiff{BrowserHTTPVersion: "h2", OurHTTPVersion: "http/1.1"},
+		},
+		{
+			"volatile and redacted values",
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("cookie", "a=1", "authorization", harRedacted, "x-a", "1", "x-a", "2")},
+			HARRequest{HTTPVersion: "h2", Headers: harHeaders("cookie", "a=2", "authorization", "Bearer t", "x-a", "1, 2")},
+			HARRequestDiff{BrowserHTTPVersion: "h2", OurHTTPVersion: "h2"},
+		},
+		{
+			"cookies",
+			HARRequest{HTTPVersion: "h2", Cookies: []HARCookie{{Name: "sid"}, {Name: "_ga"}}},
+			HARRequest{HTTPVersion: "h2", Cookies: []HARCookie{{Name: "sid"}, {Name: "extra"}}},
+			HARRequestDiff{
+				BrowserHTTPVersion: "h2", OurHTTPVersion: "h2",
+				MissingCookies: []string{"_ga"},
+				ExtraCookies:   []string{"extra"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffHARRequest(tt.browser, tt.ours)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffHARRequest() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			wantEmpty := reflect.DeepEqual(tt.want, HARRequestDiff{BrowserHTTPVersion: "h2", OurHTTPVersion: "h2"})
+			if got.Empty() != wantEmpty {
+				t.Errorf("Empty() = %v, want %v", got.Empty(), wantEmpty)
+			}
+		})
+	}
+}
+
+func TestDiffHAR(t *testing.T) {
+	entry := func(method, url string) *HAREntry {
+		return &HAREntry{Request: HARRequest{Method: method, URL: url, HTTPVersion: "h2"}}
+	}
+	browser := replayHAR(
+		entry("GET", "https://example.com/?a=1&b=2"),
+		entry("GET", "https://example.com/app.js"),
+		entry("GET", "https://example.com/app.js"),
+		entry("GET", "https://example.com/favicon.ico"),
+	)
+	ours := replayHAR(
+		entry("GET", "https://EXAMPLE.com/?b=2&a=1"),
+		entry("GET", "https://example.com/app.js"),
+		entry("POST", "https://example.com/api"),
+		entry("GET", "https://example.com/beacon"),
+	)
+
+	report := DiffHAR(browser, ours)
+	if len(report.Requests) != 2 {
+		t.Fatalf("paired %d requests, want 2", len(report.Requests))
+	}
+	wantBrowser := []string{"GET https://example.com/app.js", "GET https://example.com/favicon.ico"}
+	if !reflect.DeepEqual(report.OnlyBrowser, wantBrowser) {
+		t.Errorf("OnlyBrowser = %v, want %v", report.OnlyBrowser, wantBrowser)
+	}
+	wantOurs := []string{"GET https://example.com/beacon", "POST https://example.com/api"}
+	if !reflect.DeepEqual(report.OnlyOurs, wantOurs) {
+		t.Errorf("OnlyOurs = %v, want %v", report.OnlyOurs, wantOurs)
+	}
+
+	var out strings.Builder
+	if _, err := report.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"= GET https://example.com/?a=1&b=2",
+		"< only in browser: GET https://example.com/favicon.ico",
+		"> only in ours: POST https://example.com/api",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report missing %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package browserclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoHAREntry é devolvido no modo estrito quando nenhuma entrada casa
+var ErrNoHAREntry = errors.New("no matching HAR entry")
+
+// ErrRedactedHAREntry é devolvido quando a entrada tem Set-Cookie mascarado:
+// servi-la gravaria "[REDACTED]" como valor do cookie e quebraria a sessão
+var ErrRedactedHAREntry = errors.New("HAR entry has redacted Set-Cookie")
+
+// Headers de resposta que não fazem sentido no replay: o corpo do HAR já
+// vem decodificado e o tamanho é recalculado
+var replaySkippedHeaders = map[string]bool{
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+}
+
+// HARReplayer é um RoundTripper que responde a partir de entradas HAR, sem
+// rede. Entradas com a mesma chave são servidas na ordem gravada; depois da
+// última, ela se repete.
+type HARReplayer struct {
+	// Compara também o corpo da requisição (JSON é comparado sem formatação)
+	MatchBody bool
+	// Requisições sem entrada falham com ErrNoHAREntry em vez de receber 404
+	Strict bool
+	// Serve entradas com Set-Cookie mascarado sem esses headers, em vez de
+	// falhar com ErrRedactedHAREntry
+	AllowRedacted bool
+
+	mu      sync.Mutex
+	entries []*replayEntry
+}
+
+type replayEntry struct {
+	entry  *HAREntry
+	source string
+	key    string
+	used   int
+}
+
+// HARUnusedEntry descreve uma entrada que nunca foi servida
+type HARUnusedEntry struct {
+	Source string
+	Method string
+	URL    string
+}
+
+// NewHARReplayer carrega um arquivo HAR ou todos os .har de um diretório
+func NewHARReplayer(path string) (*HARReplayer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.har")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	r := &HARReplayer{}
+	for _, file := range files {
+		har, err := LoadHAR(file)
+		if err != nil {
+			return nil, err
+		}
+		r.add(har, filepath.Base(file))
+	}
+	return r, nil
+}
+
+// LoadHAR lê um arquivo HAR (nosso ou exportado pelo DevTools)
+func LoadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR %s: %w", path, err)
+	}
+	return &har, nil
+}
+
+// NewHARReplayerFromHAR usa um HAR já carregado (ex.: HARRecorder.HAR())
+func NewHARReplayerFromHAR(har *HAR) *HARReplayer {
+	r := &HARReplayer{}
+	r.add(har, "")
+	return r
+}
+
+func (r *HARReplayer) add(har *HAR, source string) {
+	for _, e := range har.Log.Entries {
+		key, err := replayKey(e.Request.Method, e.Request.URL)
+		if err != nil {
+			continue
+		}
+		r.entries = append(r.entries, &replayEntry{entry: e, source: source, key: key})
+	}
+}
+
+// replayKey normaliza método e URL: sem fragmento, host minúsculo e query ordenada
+func replayKey(method, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Fragment = ""
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = u.Query().Encode()
+	if method == "" {
+		method = http.MethodGet
+	}
+	return strings.ToUpper(method) + " " + u.String(), nil
+}
+
+func (r *HARReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := replayKey(req.Method, req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if r.MatchBody {
+		if body, err = readReplayBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	match := r.find(key, body)
+	if match != nil {
+		match.used++
+	}
+	r.mu.Unlock()
+
+	if match == nil {
+		if r.Strict {
+			return nil, fmt.Errorf("%w: %s", ErrNoHAREntry, key)
+		}
+		return &http.Response{
+			Status:        "404 Not Found",
+			StatusCode:    http.StatusNotFound,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			Body:          io.NopCloser(strings.NewReader("no HAR entry for " + key)),
+			ContentLength: int64(len("no HAR entry for " + key)),
+			Request:       req,
+		}, nil
+	}
+	return replayResponse(match.entry, req, r.AllowRedacted)
+}
+
+// find prefere a primeira entrada ainda não usada; sem nenhuma, repete a última
+func (r *HARReplayer) find(key string, body []byte) *replayEntry {
+	var last *replayEntry
+	for _, e := range r.entries {
+		if e.key != key {
+			continue
+		}
+		if r.MatchBody && !bodiesMatch(e.entry.Request.PostData, body) {
+			continue
+		}
+		if e.used == 0 {
+			return e
+		}
+		last = e
+	}
+	return last
+}
+
+// Unused lista as entradas que nenhuma requisição consumiu
+func (r *HARReplayer) Unused() []HARUnusedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []HARUnusedEntry
+	for _, e := range r.entries {
+		if e.used == 0 {
+			unused = append(unused, HARUnusedEntry{Source: e.source, Method: e.entry.Request.Method, URL: e.entry.Request.URL})
+		}
+	}
+	return unused
+}
+
+// Reset marca todas as entradas como não usadas
+func (r *HARReplayer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		e.used = 0
+	}
+}
+
+// readReplayBody lê o corpo e o devolve intacto à requisição
+func readReplayBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func bodiesMatch(postData *HARPostData, body []byte) bool {
+	var recorded string
+	if postData != nil {
+		recorded = postData.Text
+	}
+	if recorded == string(body) {
+		return true
+	}
+
+	var a, b interface{}
+	if json.Unmarshal([]byte(recorded), &a) != nil || json.Unmarshal(body, &b) != nil {
+		return false
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func replayResponse(e *HAREntry, req *http.Request, allowRedacted bool) (*http.Response, error) {
+	if e.Error != "" {
+		return nil, fmt.Errorf("recorded error: %s", e.Error)
+	}
+
+	var body []byte
+	content := e.Response.Content
+	if content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 body in HAR entry %s: %w", e.Request.URL, err)
+		}
+		body = decoded
+	} else {
+		body = []byte(content.Text)
+	}
+
+	header := make(http.Header)
+	for _, h := range e.Response.Headers {
+		// Pseudo-headers do HTTP/2 exportados por alguns navegadores
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		name := http.CanonicalHeaderKey(h.Name)
+		if replaySkippedHeaders[name] {
+			continue
+		}
+		if name == "Set-Cookie" && h.Value == harRedacted {
+			if !allowRedacted {
+				return nil, fmt.Errorf("%w: %s %s", ErrRedactedHAREntry, e.Request.Method, e.Request.URL)
+			}
+			continue
+		}
+		header.Add(name, h.Value)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	resp := &http.Response{
+		StatusCode:    e.Response.Status,
+		Status:        strings.TrimSpace(fmt.Sprintf("%d %s", e.Response.Status, e.Response.StatusText)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if strings.EqualFold(e.Response.HTTPVersion, "http/2.0") || strings.EqualFold(e.Response.HTTPVersion, "h2") {
+		resp.Proto, resp.ProtoMajor, resp.ProtoMinor = "HTTP/2.0", 2, 0
+	}
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
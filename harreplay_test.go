@@ -0,0 +1,172 @@
+package browserclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReplayKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		a, b   string
+		same   bool
+	}{
+		{"query order", "GET", "https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2", true},
+		{"host case", "GET", "https://EXAMPLE.com/a", "https://example.com/a", true},
+		{"scheme case", "GET", "HTTPS://example.com/a", "https://example.com/a", true},
+		{"fragment dropped", "GET", "https://example.com/a#top", "https://example.com/a", true},
+		{"path case kept", "GET", "https://example.com/A", "https://example.com/a", false},
+		{"different query", "GET", "https://example.com/a?x=1", "https://example.com/a?x=2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := replayKey(tt.method, tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := replayKey(tt.method, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (a == b) != tt.same {
+				t.Errorf("replayKey: %q vs %q, same = %v", a, b, tt.same)
+			}
+		})
+	}
+
+	empty, _ := replayKey("", "https://example.com/")
+	lower, _ := replayKey("get", "https://example.com/")
+	if empty != lower || !strings.HasPrefix(empty, "GET ") {
+		t.Errorf("method normalization: %q, %q", empty, lower)
+	}
+}
+
+func TestBodiesMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		postData *HARPostData
+		body     string
+		want     bool
+	}{
+		{"equal text", &HARPostData{Text: "a=1&b=2"}, "a=1&b=2", true},
+		{"different text", &HARPostData{Text: "a=1&b=2"}, "b=2&a=1", false},
+		{"json formatting", &HARPostData{Text: "{\n  \"a\": 1,\n  \"b\": [1, 2]\n}"}, `{"b":[1,2],"a":1}`, true},
+		{"json different value", &HARPostData{Text: `{"a":1}`}, `{"a":2}`, false},
+		{"no post data, no body", nil, "", true},
+		{"no post data, body", nil, "x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bodiesMatch(tt.postData, []byte(tt.body)); got != tt.want {
+				t.Errorf("bodiesMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func replayHAR(entries ...*HAREntry) *HAR {
+	return &HAR{Log: HARLog{Version: "1.2", Entries: entries}}
+}
+
+func replayHAREntry(method, url, body string, headers ...HARNameValue) *HAREntry {
+	return &HAREntry{
+		Request:  HARRequest{Method: method, URL: url},
+		Response: HARResponse{Status: 200, Headers: headers, Content: HARContent{Text: body}},
+	}
+}
+
+func TestHARReplayerRoundTrip(t *testing.T) {
+	r := NewHARReplayerFromHAR(replayHAR(
+		replayHAREntry("GET", "https://example.com/page?a=1&b=2", "first"),
+		replayHAREntry("GET", "https://example.com/page?b=2&a=1", "second", HARNameValue{Name: "content-encoding", Value: "br"}),
+		replayHAREntry("GET", "https://example.com/login", "", HARNameValue{Name: "Set-Cookie", Value: harRedacted}),
+	))
+
+	get := func(url string) (string, *http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		resp, err := r.RoundTrip(req)
+		if err != nil {
+			return "", nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(body), resp, nil
+	}
+
+	// Mesma chave: servidas na ordem gravada, depois a última se repete
+	for _, want := range []string{"first", "second", "second"} {
+		body, resp, err := get("https://example.com/page?b=2&a=1#x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body != want {
+			t.Errorf("body = %q, want %q", body, want)
+		}
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Error("Content-Encoding should not be replayed")
+		}
+	}
+
+	if _, resp, err := get("https://example.com/missing"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing entry: resp %v, err %v", resp, err)
+	}
+	r.Strict = true
+	if _, _, err := get("https://example.com/missing"); !errors.Is(err, ErrNoHAREntry) {
+		t.Errorf("strict missing entry: err = %v", err)
+	}
+
+	if _, _, err := get("https://example.com/login"); !errors.Is(err, ErrRedactedHAREntry) {
+		t.Errorf("redacted entry: err = %v", err)
+	}
+	r.AllowRedacted = true
+	if _, resp, err := get("https://example.com/login"); err != nil || resp.Header.Get("Set-Cookie") != "" {
+		t.Errorf("AllowRedacted: resp %v, err %v", resp, err)
+	}
+
+	if unused := r.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %v", unused)
+	}
+	r.Reset()
+	if unused := r.Unused(); len(unused) != 3 {
+		t.Errorf("Unused() after Reset = %d entries, want 3", len(unused))
+	}
+}
+
+func TestHARReplayerMatchBody(t *testing.T) {
+	r := NewHARReplayerFromHAR(replayHAR(
+		&HAREntry{Request: HARRequest{Method: "POST", URL: "https://example.com/api", PostData: &HARPostData{Text: `{"q":"a"}`}}, Response: HARResponse{Status: 200, Content: HARContent{Text: "A"}}},
+		&HAREntry{Request: HARRequest{Method: "POST", URL: "https://example.com/api", PostData: &HARPostData{Text: `{"q":"b"}`}}, Response: HARResponse{Status: 200, Content: HARContent{Text: "B"}}},
+	))
+	r.MatchBody = true
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"q": "b"}`, "B"},
+		{`{"q":"a"}`, "A"},
+		{`{"q":"c"}`, "no HAR entry"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "https://example.com/api", strings.NewReader(tt.body))
+		resp, err := r.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.HasPrefix(string(body), tt.want) {
+			t.Errorf("body %s: got %q, want %q", tt.body, body, tt.want)
+		}
+		// O corpo continua legível depois do match
+		if sent, _ := req.GetBody(); sent != nil {
+			if data, _ := io.ReadAll(sent); string(data) != tt.body {
+				t.Errorf("request body changed to %q", data)
+			}
+		}
+	}
+}
@@ -1,301 +1,377 @@
-package browserclient
-
-import (
-	"fmt"
-	"math/rand"
-	"net/http"
-	"strings"
-	"time"
-)
-
-// Ordem de headers típica por navegador
-var headerOrder = map[string][]string{
-	"Chrome": {
-		"Host",
-		"Connection",
-		"Cache-Control",
-		"Sec-Ch-Ua",
-		"Sec-Ch-Ua-Mobile",
-		"Sec-Ch-Ua-Platform",
-		"Upgrade-Insecure-Requests",
-		"User-Agent",
-		"Accept",
-		"Sec-Fetch-Site",
-		"Sec-Fetch-Mode",
-		"Sec-Fetch-User",
-		"Sec-Fetch-Dest",
-		"Accept-Encoding",
-		"Accept-Language",
-		"Cookie",
-	},
-	"Firefox": {
-		"Host",
-		"User-Agent",
-		"Accept",
-		"Accept-Language",
-		"Accept-Encoding",
-		"Connection",
-		"Upgrade-Insecure-Requests",
-		"Sec-Fetch-Dest",
-		"Sec-Fetch-Mode",
-		"Sec-Fetch-Site",
-		"Sec-Fetch-User",
-		"Cache-Control",
-		"Cookie",
-	},
-	"Safari": {
-		"Host",
-		"Accept-Encoding",
-		"Accept",
-		"User-Agent",
-		"Accept-Language",
-		"Connection",
-		"Cookie",
-	},
-}
-
-// Headers context-aware
-type HeaderBuilder struct {
-	profile     *BrowserProfile
-	isNavigate  bool
-	referrer    string
-	origin      string
-}
-
-func NewHeaderBuilder(profile *BrowserProfile) *HeaderBuilder {
-	return &HeaderBuilder{
-		profile:    profile,
-		isNavigate: true,
-	}
-}
-
-func (hb *HeaderBuilder) SetContext(isNavigate bool, referrer, origin string) {
-	hb.isNavigate = isNavigate
-	hb.referrer = referrer
-	hb.origin = origin
-}
-
-func (hb *HeaderBuilder) BuildHeaders(req *http.Request) {
-	browser := detectBrowser(hb.profile.UserAgent)
-	headers := hb.generateHeaders(req, browser)
-	
-	// Limpar headers existentes
-	req.Header = make(http.Header)
-	
-	// Aplicar headers na ordem correta
-	order := headerOrder[browser]
-	if order == nil {
-		order = headerOrder["Chrome"] // fallback
-	}
-	
-	// Adicionar headers ordenados
-	for _, key := range order {
-		if value, exists := headers[key]; exists {
-			req.Header[key] = value
-		}
-	}
-	
-	// Adicionar headers não ordenados
-	for key, value := range headers {
-		if req.Header.Get(key) == "" {
-			req.Header[key] = value
-		}
-	}
-}
-
-func (hb *HeaderBuilder) generateHeaders(req *http.Request, browser string) map[string][]string {
-	headers := make(map[string][]string)
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	
-	// Headers comuns
-	headers["User-Agent"] = []string{hb.profile.UserAgent}
-	headers["Accept-Language"] = []string{hb.profile.Language}
-	headers["Connection"] = []string{"keep-alive"}
-	
-	// Accept header baseado no contexto
-	if hb.isNavigate {
-		headers["Accept"] = []string{getNavigationAccept(browser)}
-	} else {
-		headers["Accept"] = []string{getResourceAccept(req.URL.Path)}
-	}
-	
-	// Accept-Encoding
-	headers["Accept-Encoding"] = []string{getAcceptEncoding(browser)}
-	
-	// Headers específicos do navegador
-	switch browser {
-	case "Chrome":
-		hb.addChromeHeaders(headers, r)
-	case "Firefox":
-		hb.addFirefoxHeaders(headers, r)
-	case "Safari":
-		hb.addSafariHeaders(headers, r)
-	}
-	
-	// Headers condicionais
-	if hb.referrer != "" {
-		headers["Referer"] = []string{hb.referrer}
-	}
-	
-	if hb.origin != "" && !hb.isNavigate {
-		headers["Origin"] = []string{hb.origin}
-	}
-	
-	// Headers aleatórios
-	if r.Float32() < 0.3 {
-		headers["DNT"] = []string{"1"}
-	}
-	
-	if r.Float32() < 0.2 {
-		headers["Cache-Control"] = []string{"no-cache"}
-	} else if r.Float32() < 0.4 {
-		headers["Cache-Control"] = []string{"max-age=0"}
-	}
-	
-	return headers
-}
-
-func (hb *HeaderBuilder) addChromeHeaders(headers map[string][]string, r *rand.Rand) {
-	// Extrair versão do Chrome
-	version := "126"
-	if matches := strings.Split(hb.profile.UserAgent, "Chrome/"); len(matches) > 1 {
-		if parts := strings.Split(matches[1], "."); len(parts) > 0 {
-			version = parts[0]
-		}
-	}
-	
-	// Sec-CH-UA headers
-	headers["Sec-Ch-Ua"] = []string{fmt.Sprintf(`"Not)A;Brand";v="99", "Google Chrome";v="%s", "Chromium";v="%s"`, version, version)}
-	headers["Sec-Ch-Ua-Mobile"] = []string{"?0"}
-	
-	// Platform baseado no User-Agent
-	platform := "Windows"
-	if strings.Contains(hb.profile.UserAgent, "Macintosh") {
-		platform = "macOS"
-	} else if strings.Contains(hb.profile.UserAgent, "X11") {
-		platform = "Linux"
-	}
-	headers["Sec-Ch-Ua-Platform"] = []string{fmt.Sprintf(`"%s"`, platform)}
-	
-	// Sec-Fetch headers
-	headers["Sec-Fetch-Site"] = []string{hb.getSecFetchSite()}
-	headers["Sec-Fetch-Mode"] = []string{hb.getSecFetchMode()}
-	headers["Sec-Fetch-Dest"] = []string{hb.getSecFetchDest()}
-	
-	if hb.isNavigate {
-		headers["Sec-Fetch-User"] = []string{"?1"}
-		headers["Upgrade-Insecure-Requests"] = []string{"1"}
-	}
-	
-	// Chrome às vezes envia Sec-CH-UA-Platform-Version
-	if r.Float32() < 0.3 {
-		headers["Sec-Ch-Ua-Platform-Version"] = []string{`"10.0.0"`}
-	}
-}
-
-func (hb *HeaderBuilder) addFirefoxHeaders(headers map[string][]string, r *rand.Rand) {
-	headers["Upgrade-Insecure-Requests"] = []string{"1"}
-	
-	// Firefox Sec-Fetch headers (mais recentes)
-	version := 126
-	if matches := strings.Split(hb.profile.UserAgent, "Firefox/"); len(matches) > 1 {
-		if parts := strings.Split(matches[1], "."); len(parts) > 0 {
-			fmt.Sscanf(parts[0], "%d", &version)
-		}
-	}
-	
-	if version >= 90 {
-		headers["Sec-Fetch-Dest"] = []string{hb.getSecFetchDest()}
-		headers["Sec-Fetch-Mode"] = []string{hb.getSecFetchMode()}
-		headers["Sec-Fetch-Site"] = []string{hb.getSecFetchSite()}
-		if hb.isNavigate {
-			headers["Sec-Fetch-User"] = []string{"?1"}
-		}
-	}
-	
-	// TE header específico do Firefox
-	if r.Float32() < 0.7 {
-		headers["TE"] = []string{"trailers"}
-	}
-}
-
-func (hb *HeaderBuilder) addSafariHeaders(headers map[string][]string, r *rand.Rand) {
-	// Safari tem menos headers especiais
-	if hb.isNavigate {
-		headers["Upgrade-Insecure-Requests"] = []string{"1"}
-	}
-	
-	// Safari não usa Sec-Fetch headers
-	// Mas tem ordem específica de Accept-Encoding
-	headers["Accept-Encoding"] = []string{"gzip, deflate, br"}
-}
-
-func (hb *HeaderBuilder) getSecFetchSite() string {
-	if hb.referrer == "" {
-		return "none"
-	}
-	if hb.origin != "" && strings.HasPrefix(hb.referrer, hb.origin) {
-		return "same-origin"
-	}
-	return "cross-site"
-}
-
-func (hb *HeaderBuilder) getSecFetchMode() string {
-	if hb.isNavigate {
-		return "navigate"
-	}
-	return "no-cors"
-}
-
-func (hb *HeaderBuilder) getSecFetchDest() string {
-	if hb.isNavigate {
-		return "document"
-	}
-	return "empty"
-}
-
-func detectBrowser(userAgent string) string {
-	if strings.Contains(userAgent, "Firefox") {
-		return "Firefox"
-	}
-	if strings.Contains(userAgent, "Safari") && !strings.Contains(userAgent, "Chrome") {
-		return "Safari"
-	}
-	if strings.Contains(userAgent, "Edg/") {
-		return "Edge"
-	}
-	return "Chrome"
-}
-
-func getNavigationAccept(browser string) string {
-	switch browser {
-	case "Firefox":
-		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
-	case "Safari":
-		return "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
-	default: // Chrome/Edge
-		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
-	}
-}
-
-func getResourceAccept(path string) string {
-	ext := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
-	switch ext {
-	case "js":
-		return "*/*"
-	case "css":
-		return "text/css,*/*;q=0.1"
-	case "jpg", "jpeg", "png", "gif", "webp":
-		return "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
-	default:
-		return "*/*"
-	}
-}
-
-func getAcceptEncoding(browser string) string {
-	if browser == "Safari" {
-		return "gzip, deflate, br"
-	}
-	// Chrome/Firefox/Edge
-	return "gzip, deflate, br, zstd"
+package browserclient
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ordem de headers do Chrome, herdada pelos demais navegadores Chromium
+var chromiumHeaderOrder = []string{
+	"Host",
+	"Connection",
+	"Pragma",
+	"Cache-Control",
+	"Sec-Ch-Ua",
+	"Sec-Ch-Ua-Mobile",
+	"Sec-Ch-Ua-Platform",
+	"Sec-Ch-Ua-Full-Version",
+	"Sec-Ch-Ua-Arch",
+	"Sec-Ch-Ua-Platform-Version",
+	"Sec-Ch-Ua-Model",
+	"Sec-Ch-Ua-Bitness",
+	"Sec-Ch-Ua-Wow64",
+	"Sec-Ch-Ua-Full-Version-List",
+	"Sec-Ch-Ua-Form-Factors",
+	"Device-Memory",
+	"Sec-Ch-Device-Memory",
+	"Dpr",
+	"Sec-Ch-Dpr",
+	"Viewport-Width",
+	"Sec-Ch-Viewport-Width",
+	"Sec-Ch-Viewport-Height",
+	"Rtt",
+	"Downlink",
+	"Ect",
+	"Sec-Ch-Prefers-Color-Scheme",
+	"Sec-Ch-Prefers-Reduced-Motion",
+	"Upgrade-Insecure-Requests",
+	"User-Agent",
+	"Accept",
+	"Sec-Fetch-Site",
+	"Sec-Fetch-Mode",
+	"Sec-Fetch-User",
+	"Sec-Fetch-Dest",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Cookie",
+}
+
+// Ordem de headers típica por navegador
+var headerOrder = map[string][]string{
+	"Chrome":  chromiumHeaderOrder,
+	"Edge":    chromiumHeaderOrder,
+	"Opera":   chromiumHeaderOrder,
+	"Samsung": chromiumHeaderOrder,
+	// O Brave envia Sec-GPC logo depois do Accept
+	"Brave": withHeaderAfter(chromiumHeaderOrder, "Accept", "Sec-Gpc"),
+	"Firefox": {
+		"Host",
+		"User-Agent",
+		"Accept",
+		"Accept-Language",
+		"Accept-Encoding",
+		"Connection",
+		"Upgrade-Insecure-Requests",
+		"Sec-Fetch-Dest",
+		"Sec-Fetch-Mode",
+		"Sec-Fetch-Site",
+		"Sec-Fetch-User",
+		"Pragma",
+		"Cache-Control",
+		"Cookie",
+	},
+	"Safari": {
+		"Host",
+		"Accept-Encoding",
+		"Accept",
+		"User-Agent",
+		"Accept-Language",
+		"Cache-Control",
+		"Pragma",
+		"Connection",
+		"Cookie",
+	},
+}
+
+// withHeaderAfter copia order inserindo name logo depois de after
+func withHeaderAfter(order []string, after, name string) []string {
+	result := make([]string, 0, len(order)+1)
+	for _, key := range order {
+		result = append(result, key)
+		if key == after {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// ReloadMode indica se a navegação é um recarregamento
+type ReloadMode int
+
+const (
+	// ReloadNone é uma navegação normal
+	ReloadNone ReloadMode = iota
+	// ReloadNormal (F5) envia Cache-Control: max-age=0 e revalida o cache
+	ReloadNormal
+	// ReloadHard (Ctrl+F5) envia no-cache e ignora o cache
+	ReloadHard
+)
+
+// Headers context-aware
+type HeaderBuilder struct {
+	profile     *BrowserProfile
+	isNavigate  bool
+	referrer    string
+	origin      string
+	reload      ReloadMode
+	// Accept-CH por origem (compartilhado com o BrowserClient)
+	hints *clientHintsStore
+}
+
+func NewHeaderBuilder(profile *BrowserProfile) *HeaderBuilder {
+	return &HeaderBuilder{
+		profile:    profile,
+		isNavigate: true,
+	}
+}
+
+func (hb *HeaderBuilder) SetContext(isNavigate bool, referrer, origin string) {
+	hb.isNavigate = isNavigate
+	hb.referrer = referrer
+	hb.origin = origin
+}
+
+// SetReload define o modo de recarregamento da próxima requisição
+func (hb *HeaderBuilder) SetReload(mode ReloadMode) {
+	hb.reload = mode
+}
+
+func (hb *HeaderBuilder) BuildHeaders(req *http.Request) {
+	browser := profileBrowser(hb.profile)
+	headers := hb.generateHeaders(req, browser)
+	
+	// Limpar headers existentes
+	req.Header = make(http.Header)
+	
+	// Aplicar headers na ordem correta
+	order := headerOrder[browser]
+	if order == nil {
+		order = headerOrder["Chrome"] // fallback
+	}
+	
+	// Adicionar headers ordenados
+	for _, key := range order {
+		if value, exists := headers[key]; exists {
+			req.Header[key] = value
+		}
+	}
+	
+	// Adicionar headers não ordenados
+	for key, value := range headers {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = value
+		}
+	}
+}
+
+func (hb *HeaderBuilder) generateHeaders(req *http.Request, browser string) map[string][]string {
+	headers := make(map[string][]string)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	
+	// Headers comuns
+	headers["User-Agent"] = []string{hb.profile.UserAgent}
+	headers["Accept-Language"] = []string{hb.profile.Language}
+	headers["Connection"] = []string{"keep-alive"}
+	
+	// Accept header baseado no contexto
+	if hb.isNavigate {
+		headers["Accept"] = []string{getNavigationAccept(browser)}
+	} else {
+		headers["Accept"] = []string{getResourceAccept(req.URL.Path)}
+	}
+	
+	// Accept-Encoding
+	headers["Accept-Encoding"] = []string{getAcceptEncoding(browser)}
+	
+	// Headers específicos do navegador
+	switch browser {
+	case "Chrome", "Edge", "Opera", "Brave", "Samsung":
+		hb.addChromeHeaders(headers, r)
+		hb.addClientHints(headers, req.URL)
+		if browser == "Brave" {
+			// Global Privacy Control vem ligado por padrão
+			headers["Sec-Gpc"] = []string{"1"}
+		}
+	case "Firefox":
+		hb.addFirefoxHeaders(headers, r)
+	case "Safari":
+		hb.addSafariHeaders(headers, r)
+	}
+	
+	// Headers condicionais
+	if hb.referrer != "" {
+		headers["Referer"] = []string{hb.referrer}
+	}
+	
+	if hb.origin != "" && !hb.isNavigate {
+		headers["Origin"] = []string{hb.origin}
+	}
+	
+	// Headers aleatórios
+	if r.Float32() < 0.3 {
+		headers["DNT"] = []string{"1"}
+	}
+	
+	// Cache-Control só aparece em recarregamentos, como nos navegadores
+	switch hb.reload {
+	case ReloadNormal:
+		headers["Cache-Control"] = []string{"max-age=0"}
+	case ReloadHard:
+		headers["Pragma"] = []string{"no-cache"}
+		headers["Cache-Control"] = []string{"no-cache"}
+	}
+	
+	return headers
+}
+
+func (hb *HeaderBuilder) addChromeHeaders(headers map[string][]string, r *rand.Rand) {
+	// Sec-CH-UA de baixa entropia: sempre enviados. Os demais só depois de
+	// um Accept-CH da origem (addClientHints).
+	headers["Sec-Ch-Ua"] = []string{profileBrandList(hb.profile, false)}
+	headers["Sec-Ch-Ua-Mobile"] = []string{mobileHint(hb.profile)}
+	headers["Sec-Ch-Ua-Platform"] = []string{fmt.Sprintf(`"%s"`, uaPlatform(hb.profile.UserAgent))}
+	
+	// Sec-Fetch headers
+	headers["Sec-Fetch-Site"] = []string{hb.getSecFetchSite()}
+	headers["Sec-Fetch-Mode"] = []string{hb.getSecFetchMode()}
+	headers["Sec-Fetch-Dest"] = []string{hb.getSecFetchDest()}
+	
+	if hb.isNavigate {
+		headers["Sec-Fetch-User"] = []string{"?1"}
+		headers["Upgrade-Insecure-Requests"] = []string{"1"}
+	}
+}
+
+func (hb *HeaderBuilder) addFirefoxHeaders(headers map[string][]string, r *rand.Rand) {
+	headers["Upgrade-Insecure-Requests"] = []string{"1"}
+	
+	// Firefox Sec-Fetch headers (mais recentes)
+	version := 126
+	if matches := strings.Split(hb.profile.UserAgent, "Firefox/"); len(matches) > 1 {
+		if parts := strings.Split(matches[1], "."); len(parts) > 0 {
+			fmt.Sscanf(parts[0], "%d", &version)
+		}
+	}
+	
+	if version >= 90 {
+		headers["Sec-Fetch-Dest"] = []string{hb.getSecFetchDest()}
+		headers["Sec-Fetch-Mode"] = []string{hb.getSecFetchMode()}
+		headers["Sec-Fetch-Site"] = []string{hb.getSecFetchSite()}
+		if hb.isNavigate {
+			headers["Sec-Fetch-User"] = []string{"?1"}
+		}
+	}
+	
+	// TE header específico do Firefox
+	if r.Float32() < 0.7 {
+		headers["TE"] = []string{"trailers"}
+	}
+}
+
+func (hb *HeaderBuilder) addSafariHeaders(headers map[string][]string, r *rand.Rand) {
+	// Safari tem menos headers especiais
+	if hb.isNavigate {
+		headers["Upgrade-Insecure-Requests"] = []string{"1"}
+	}
+	
+	// Safari não usa Sec-Fetch headers
+	// Mas tem ordem específica de Accept-Encoding
+	headers["Accept-Encoding"] = []string{"gzip, deflate, br"}
+}
+
+func (hb *HeaderBuilder) getSecFetchSite() string {
+	if hb.referrer == "" {
+		return "none"
+	}
+	if hb.origin != "" && strings.HasPrefix(hb.referrer, hb.origin) {
+		return "same-origin"
+	}
+	return "cross-site"
+}
+
+func (hb *HeaderBuilder) getSecFetchMode() string {
+	if hb.isNavigate {
+		return "navigate"
+	}
+	return "no-cors"
+}
+
+func (hb *HeaderBuilder) getSecFetchDest() string {
+	if hb.isNavigate {
+		return "document"
+	}
+	return "empty"
+}
+
+// detectBrowser deduz a família pelo UA. Edge e Opera acrescentam o próprio
+// token ao UA do Chrome, então são testados antes; o Brave não se distingue.
+func detectBrowser(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "Firefox/"):
+		return "Firefox"
+	case strings.Contains(userAgent, "Edg/"):
+		return "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		return "Opera"
+	case strings.Contains(userAgent, "SamsungBrowser/"):
+		return "Samsung"
+	case strings.Contains(userAgent, "Safari") && !strings.Contains(userAgent, "Chrome"):
+		return "Safari"
+	}
+	return "Chrome"
+}
+
+// mobileHint é o Sec-Ch-Ua-Mobile do perfil
+func mobileHint(profile *BrowserProfile) string {
+	if profile.Mobile || strings.Contains(profile.UserAgent, " Mobile") {
+		return "?1"
+	}
+	return "?0"
+}
+
+// profileBrowser é a família do perfil: a declarada ou a deduzida do UA
+func profileBrowser(profile *BrowserProfile) string {
+	if profile.Browser != "" {
+		return profile.Browser
+	}
+	return detectBrowser(profile.UserAgent)
+}
+
+func getNavigationAccept(browser string) string {
+	switch browser {
+	case "Firefox":
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	case "Safari":
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	case "Brave":
+		// Sem signed exchanges (SXG desativado no Brave)
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8"
+	default: // Chrome/Edge/Opera/Samsung
+		return "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	}
+}
+
+func getResourceAccept(path string) string {
+	ext := strings.ToLower(path[strings.LastIndex(path, ".")+1:])
+	switch ext {
+	case "js":
+		return "*/*"
+	case "css":
+		return "text/css,*/*;q=0.1"
+	case "jpg", "jpeg", "png", "gif", "webp":
+		return "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+	default:
+		return "*/*"
+	}
+}
+
+func getAcceptEncoding(browser string) string {
+	if browser == "Safari" {
+		return "gzip, deflate, br"
+	}
+	// Chrome/Firefox/Edge/Opera/Brave/Samsung
+	return "gzip, deflate, br, zstd"
 }
\ No newline at end of file
@@ -0,0 +1,242 @@
+package browserclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAddrMaxFailures   = 3
+	defaultAddrBlockDuration = 10 * time.Minute
+)
+
+// LocalAddrPolicy define como os endereços de origem são distribuídos
+type LocalAddrPolicy int
+
+const (
+	// LocalAddrSticky mantém cada perfil (ThreadID) no mesmo endereço
+	LocalAddrSticky LocalAddrPolicy = iota
+	// LocalAddrRoundRobin alterna o endereço a cada conexão
+	LocalAddrRoundRobin
+)
+
+// LocalAddrPool distribui conexões entre vários IPs locais da máquina.
+// Pode ser compartilhado entre vários BrowserClient.
+type LocalAddrPool struct {
+	Policy LocalAddrPolicy
+	// Falhas consecutivas de dial até o endereço ser bloqueado
+	MaxFailures int
+	// Tempo que um endereço bloqueado fica fora de uso
+	BlockDuration time.Duration
+	// Status HTTP que indicam bloqueio do IP de origem (ex.: 403, 429)
+	BlockStatusCodes []int
+
+	mu     sync.Mutex
+	addrs  []*localAddrState
+	next   int
+	sticky map[string]*localAddrState
+}
+
+type localAddrState struct {
+	ip           net.IP
+	failures     int
+	blockedUntil time.Time
+	// Destinos distintos que falharam desde o último sucesso
+	failedRemotes map[string]struct{}
+}
+
+// LocalAddrStatus é um retrato do estado de um endereço do pool
+type LocalAddrStatus struct {
+	IP           net.IP
+	Failures     int
+	BlockedUntil time.Time
+}
+
+// NewLocalAddrPool cria um pool a partir de uma lista de IPs
+func NewLocalAddrPool(addrs []string, policy LocalAddrPolicy) (*LocalAddrPool, error) {
+	pool := &LocalAddrPool{
+		Policy: policy,
+		sticky: make(map[string]*localAddrState),
+	}
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address %q", a)
+		}
+		pool.addrs = append(pool.addrs, &localAddrState{ip: ip})
+	}
+	if len(pool.addrs) == 0 {
+		return nil, fmt.Errorf("local address pool is empty")
+	}
+	return pool, nil
+}
+
+// NewLocalAddrPoolFromInterface usa todos os endereços globais de uma interface
+func NewLocalAddrPoolFromInterface(name string, policy LocalAddrPolicy) (*LocalAddrPool, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	ifAddrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+
+	var addrs []string
+	for _, a := range ifAddrs {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+			addrs = append(addrs, ipNet.IP.String())
+		}
+	}
+	return NewLocalAddrPool(addrs, policy)
+}
+
+// pick escolhe um endereço saudável da mesma família do destino
+func (p *LocalAddrPool) pick(key string, v4 bool) net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	usable := func(s *localAddrState) bool {
+		return (s.ip.To4() != nil) == v4 && now.After(s.blockedUntil)
+	}
+
+	stickyKey := fmt.Sprintf("%s|%v", key, v4)
+	if p.Policy == LocalAddrSticky {
+		if s, ok := p.sticky[stickyKey]; ok && usable(s) {
+			return s.ip
+		}
+	}
+
+	for i := 0; i < len(p.addrs); i++ {
+		s := p.addrs[(p.next+i)%len(p.addrs)]
+		if usable(s) {
+			p.next = (p.next + i + 1) % len(p.addrs)
+			if p.Policy == LocalAddrSticky {
+				if p.sticky == nil {
+					p.sticky = make(map[string]*localAddrState)
+				}
+				p.sticky[stickyKey] = s
+			}
+			return s.ip
+		}
+	}
+	return nil
+}
+
+func (p *LocalAddrPool) find(ip net.IP) *localAddrState {
+	for _, s := range p.addrs {
+		if s.ip.Equal(ip) {
+			return s
+		}
+	}
+	return nil
+}
+
+// MarkFailed registra uma falha de conexão; após MaxFailures o endereço é bloqueado
+func (p *LocalAddrPool) MarkFailed(ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.find(ip); s != nil {
+		p.addFailure(s)
+	}
+}
+
+// markDialFailed registra a falha de um dial a partir de ip. Só conta contra o
+// endereço de origem o que aponta para ele: erro de bind, ou falhas em destinos
+// diferentes. Um alvo fora do ar (recusando ou sem responder) não bloqueia
+// os IPs locais.
+func (p *LocalAddrPool) markDialFailed(ip, remote net.IP, err error) {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.find(ip)
+	if s == nil {
+		return
+	}
+	if errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.EADDRINUSE) {
+		p.addFailure(s)
+		return
+	}
+
+	key := remote.String()
+	if _, seen := s.failedRemotes[key]; seen {
+		return
+	}
+	if s.failedRemotes == nil {
+		s.failedRemotes = make(map[string]struct{})
+	}
+	s.failedRemotes[key] = struct{}{}
+	p.addFailure(s)
+}
+
+func (p *LocalAddrPool) addFailure(s *localAddrState) {
+	s.failures++
+
+	maxFailures := p.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultAddrMaxFailures
+	}
+	if s.failures >= maxFailures {
+		s.blockedUntil = time.Now().Add(p.blockDuration())
+	}
+}
+
+// MarkBlocked tira o endereço de uso por BlockDuration (ex.: após um 403)
+func (p *LocalAddrPool) MarkBlocked(ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.find(ip); s != nil {
+		s.blockedUntil = time.Now().Add(p.blockDuration())
+	}
+}
+
+// MarkHealthy zera as falhas do endereço
+func (p *LocalAddrPool) MarkHealthy(ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.find(ip); s != nil {
+		s.failures = 0
+		s.failedRemotes = nil
+		s.blockedUntil = time.Time{}
+	}
+}
+
+// Status retorna o estado atual de todos os endereços
+func (p *LocalAddrPool) Status() []LocalAddrStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]LocalAddrStatus, len(p.addrs))
+	for i, s := range p.addrs {
+		status[i] = LocalAddrStatus{IP: s.ip, Failures: s.failures, BlockedUntil: s.blockedUntil}
+	}
+	return status
+}
+
+func (p *LocalAddrPool) blockDuration() time.Duration {
+	if p.BlockDuration > 0 {
+		return p.BlockDuration
+	}
+	return defaultAddrBlockDuration
+}
+
+func (p *LocalAddrPool) isBlockStatus(code int) bool {
+	for _, c := range p.BlockStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
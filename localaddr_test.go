@@ -0,0 +1,78 @@
+package browserclient
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestLocalAddrPoolDialFailures(t *testing.T) {
+	local := net.ParseIP("192.0.2.10")
+	bindErr := &net.OpError{Op: "dial", Err: os.NewSyscallError("bind", syscall.EADDRNOTAVAIL)}
+	refused := &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	timeout := &net.OpError{Op: "dial", Err: fmt.Errorf("i/o timeout")}
+
+	type failure struct {
+		remote string
+		err    error
+	}
+	tests := []struct {
+		name        string
+		failures    []failure
+		wantBlocked bool
+	}{
+		{
+			name:     "refused target never counts",
+			failures: []failure{{"198.51.100.1", refused}, {"198.51.100.2", refused}, {"198.51.100.3", refused}},
+		},
+		{
+			name:     "same dead target repeated",
+			failures: []failure{{"198.51.100.1", timeout}, {"198.51.100.1", timeout}, {"198.51.100.1", timeout}, {"198.51.100.1", timeout}},
+		},
+		{
+			name:        "failures across several targets",
+			failures:    []failure{{"198.51.100.1", timeout}, {"198.51.100.2", timeout}, {"198.51.100.3", timeout}},
+			wantBlocked: true,
+		},
+		{
+			name:        "bind errors count every time",
+			failures:    []failure{{"198.51.100.1", bindErr}, {"198.51.100.1", bindErr}, {"198.51.100.1", bindErr}},
+			wantBlocked: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewLocalAddrPool([]string{local.String()}, LocalAddrSticky)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.failures {
+				pool.markDialFailed(local, net.ParseIP(f.remote), f.err)
+			}
+			blocked := pool.pick("session", true) == nil
+			if blocked != tt.wantBlocked {
+				t.Errorf("blocked = %v, want %v (status %+v)", blocked, tt.wantBlocked, pool.Status())
+			}
+		})
+	}
+}
+
+func TestLocalAddrPoolHealthyResetsRemotes(t *testing.T) {
+	local := net.ParseIP("192.0.2.10")
+	pool, err := NewLocalAddrPool([]string{local.String()}, LocalAddrSticky)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout := fmt.Errorf("i/o timeout")
+
+	pool.markDialFailed(local, net.ParseIP("198.51.100.1"), timeout)
+	pool.markDialFailed(local, net.ParseIP("198.51.100.2"), timeout)
+	pool.MarkHealthy(local)
+	pool.markDialFailed(local, net.ParseIP("198.51.100.1"), timeout)
+
+	if got := pool.Status()[0].Failures; got != 1 {
+		t.Fatalf("failures = %d, want 1 after MarkHealthy", got)
+	}
+}
@@ -0,0 +1,105 @@
+package browserclient
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Handler executa a requisição: é o próximo elo da cadeia de middlewares
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware envolve o Handler seguinte. Pode alterar a requisição, devolver
+// uma resposta sem chamar next (short-circuit) ou chamar next de novo para
+// repetir a requisição (use RewindRequest para reenviar o corpo).
+type Middleware func(rc *RequestContext, req *http.Request, next Handler) (*http.Response, error)
+
+// RequestHook é chamado antes do envio, depois do BuildHeaders
+type RequestHook func(rc *RequestContext, req *http.Request) error
+
+// ResponseHook é chamado com a resposta final de cada tentativa
+type ResponseHook func(rc *RequestContext, resp *http.Response) error
+
+// RequestContext dá aos middlewares acesso ao cliente, ao perfil e às opções
+// da requisição. O context.Context continua em req.Context().
+type RequestContext struct {
+	Client  *BrowserClient
+	Profile *BrowserProfile
+	Options RequestOptions
+	// Attempt conta as chamadas ao transport nesta requisição (começa em 1)
+	Attempt int
+}
+
+// Use adiciona middlewares à cadeia; o primeiro registrado é o mais externo
+func (bc *BrowserClient) Use(middlewares ...Middleware) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.middlewares = append(bc.middlewares, middlewares...)
+}
+
+// OnRequest registra um hook de requisição; um erro cancela o envio
+func (bc *BrowserClient) OnRequest(hook RequestHook) {
+	bc.Use(func(rc *RequestContext, req *http.Request, next Handler) (*http.Response, error) {
+		if err := hook(rc, req); err != nil {
+			return nil, err
+		}
+		return next(req)
+	})
+}
+
+// OnResponse registra um hook de resposta; um erro fecha o corpo e é devolvido
+func (bc *BrowserClient) OnResponse(hook ResponseHook) {
+	bc.Use(func(rc *RequestContext, req *http.Request, next Handler) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+		if err := hook(rc, resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// RewindRequest prepara uma cópia da requisição para ser enviada de novo,
+// recriando o corpo com GetBody
+func RewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return clone, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
+
+// runMiddlewares monta a cadeia em volta do handler final
+func (bc *BrowserClient) runMiddlewares(rc *RequestContext, req *http.Request, final Handler) (*http.Response, error) {
+	bc.mu.RLock()
+	middlewares := bc.middlewares
+	bc.mu.RUnlock()
+
+	// A política de retry é o elo mais externo: cada tentativa passa pelos
+	// middlewares do usuário (ex.: assinatura refeita a cada envio)
+	if policy := bc.retryPolicyFor(rc.Options); policy != nil {
+		middlewares = append([]Middleware{policy.Middleware()}, middlewares...)
+	}
+
+	handler := func(req *http.Request) (*http.Response, error) {
+		rc.Attempt++
+		return final(req)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		mw, next := middlewares[i], handler
+		handler = func(req *http.Request) (*http.Response, error) {
+			return mw(rc, req, next)
+		}
+	}
+	return handler(req)
+}
@@ -0,0 +1,203 @@
+package browserclient
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const defaultProfileVariance = 0.3
+
+// ThinkTime sorteia a pausa antes de uma navegação
+type ThinkTime interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+// UniformThinkTime sorteia entre Min e Max com a mesma probabilidade
+type UniformThinkTime struct {
+	Min, Max time.Duration
+}
+
+func (u UniformThinkTime) Sample(r *rand.Rand) time.Duration {
+	if u.Max <= u.Min {
+		return u.Min
+	}
+	return u.Min + time.Duration(r.Int63n(int64(u.Max-u.Min)))
+}
+
+// NormalThinkTime segue uma normal truncada em [Min, Max]
+type NormalThinkTime struct {
+	Mean, StdDev time.Duration
+	Min, Max     time.Duration
+}
+
+func (n NormalThinkTime) Sample(r *rand.Rand) time.Duration {
+	d := n.Mean + time.Duration(r.NormFloat64()*float64(n.StdDev))
+	return clampDuration(d, n.Min, n.Max)
+}
+
+// LogNormalThinkTime segue uma log-normal: a maioria das pausas fica perto da
+// mediana, com uma cauda de leituras longas, como em usuários reais
+type LogNormalThinkTime struct {
+	Median   time.Duration
+	Sigma    float64
+	Min, Max time.Duration
+}
+
+func (l LogNormalThinkTime) Sample(r *rand.Rand) time.Duration {
+	d := time.Duration(float64(l.Median) * math.Exp(r.NormFloat64()*l.Sigma))
+	return clampDuration(d, l.Min, l.Max)
+}
+
+// ExponentialThinkTime segue uma exponencial com a média informada
+type ExponentialThinkTime struct {
+	Mean     time.Duration
+	Min, Max time.Duration
+}
+
+func (e ExponentialThinkTime) Sample(r *rand.Rand) time.Duration {
+	d := time.Duration(r.ExpFloat64() * float64(e.Mean))
+	return clampDuration(d, e.Min, e.Max)
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	if d < lo {
+		d = lo
+	}
+	if hi > 0 && d > hi {
+		d = hi
+	}
+	return d
+}
+
+// PacingConfig insere pausas entre navegações (IsNavigate). Subrecursos
+// saem imediatamente.
+type PacingConfig struct {
+	// Distribuição da pausa (padrão: log-normal com mediana de 4s, 1s a 45s)
+	ThinkTime ThinkTime
+	// Cada perfil ganha um ritmo próprio: as pausas são multiplicadas por um
+	// fator fixo em [1-ProfileVariance, 1+ProfileVariance] (zero usa o padrão 0.3)
+	ProfileVariance float64
+	// NoProfileVariance dá o mesmo ritmo a todos os perfis (fator 1)
+	NoProfileVariance bool
+	// ForProfile permite uma distribuição diferente por perfil
+	ForProfile func(profile *BrowserProfile) ThinkTime
+}
+
+var defaultThinkTime = LogNormalThinkTime{
+	Median: 4 * time.Second,
+	Sigma:  0.6,
+	Min:    1 * time.Second,
+	Max:    45 * time.Second,
+}
+
+// Estado de ritmo por perfil (chave: SessionID), compartilhado entre clientes
+var profilePacers sync.Map
+
+type pacer struct {
+	mu      sync.Mutex
+	rng     *rand.Rand
+	speed   float64
+	nextNav time.Time
+	started bool
+}
+
+func getPacer(profile *BrowserProfile, config *PacingConfig) *pacer {
+	if p, ok := profilePacers.Load(profile.SessionID); ok {
+		return p.(*pacer)
+	}
+
+	variance := config.ProfileVariance
+	if variance <= 0 || variance >= 1 {
+		variance = defaultProfileVariance
+	}
+	if config.NoProfileVariance {
+		variance = 0
+	}
+
+	// Fator de velocidade derivado do SessionID: estável durante a vida do perfil
+	h := fnv.New64a()
+	h.Write([]byte(profile.SessionID))
+	seed := int64(h.Sum64())
+	speed := 1 - variance + rand.New(rand.NewSource(seed)).Float64()*2*variance
+
+	p, _ := profilePacers.LoadOrStore(profile.SessionID, &pacer{
+		rng:   rand.New(rand.NewSource(time.Now().UnixNano() ^ seed)),
+		speed: speed,
+	})
+	return p.(*pacer)
+}
+
+// wait espera a vez da próxima navegação. A primeira sai na hora; as
+// seguintes esperam a pausa sorteada contada a partir do fim da anterior.
+func (p *pacer) wait(ctx context.Context, profile *BrowserProfile, config *PacingConfig) error {
+	thinkTime := config.ThinkTime
+	if config.ForProfile != nil {
+		thinkTime = config.ForProfile(profile)
+	}
+	if thinkTime == nil {
+		thinkTime = defaultThinkTime
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	prev := p.nextNav
+	var at time.Time
+	if p.started {
+		pause := time.Duration(float64(thinkTime.Sample(p.rng)) * p.speed)
+		at = p.nextNav.Add(pause)
+	}
+	if at.Before(now) {
+		at = now
+	}
+	p.started = true
+	// Reserva o horário: navegações concorrentes do mesmo perfil entram em fila
+	p.nextNav = at
+	p.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Devolve o horário reservado, a menos que outra navegação já tenha
+		// entrado na fila depois desta
+		p.mu.Lock()
+		if p.nextNav.Equal(at) {
+			p.nextNav = prev
+		}
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// done marca o fim da navegação: a próxima pausa conta a partir daqui
+func (p *pacer) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.After(p.nextNav) {
+		p.nextNav = now
+	}
+}
+
+// paceNavigation aplica o ritmo do perfil antes de uma navegação
+func (bc *BrowserClient) paceNavigation(ctx context.Context, opts RequestOptions) (done func(), err error) {
+	config := bc.config.Pacing
+	if config == nil || !opts.IsNavigate {
+		return func() {}, nil
+	}
+
+	p := getPacer(bc.profile, config)
+	if err := p.wait(ctx, bc.profile, config); err != nil {
+		return nil, err
+	}
+	return p.done, nil
+}
@@ -0,0 +1,144 @@
+package browserclient
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestThinkTimeBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		tt       ThinkTime
+		min, max time.Duration
+	}{
+		{"uniform", UniformThinkTime{Min: time.Second, Max: 2 * time.Second}, time.Second, 2 * time.Second},
+		{"uniform degenerate", UniformThinkTime{Min: time.Second, Max: time.Second}, time.Second, time.Second},
+		{"normal", NormalThinkTime{Mean: 3 * time.Second, StdDev: 5 * time.Second, Min: time.Second, Max: 4 * time.Second}, time.Second, 4 * time.Second},
+		{"lognormal", LogNormalThinkTime{Median: 4 * time.Second, Sigma: 2, Min: time.Second, Max: 10 * time.Second}, time.Second, 10 * time.Second},
+		{"exponential", ExponentialThinkTime{Mean: 5 * time.Second, Min: 500 * time.Millisecond, Max: 8 * time.Second}, 500 * time.Millisecond, 8 * time.Second},
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				if d := tt.tt.Sample(r); d < tt.min || d > tt.max {
+					t.Fatalf("Sample() = %v, want [%v, %v]", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestPacerProfileVariance(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PacingConfig
+		min, max float64
+	}{
+		{"default", PacingConfig{}, 1 - defaultProfileVariance, 1 + defaultProfileVariance},
+		{"custom", PacingConfig{ProfileVariance: 0.1}, 0.9, 1.1},
+		{"disabled", PacingConfig{NoProfileVariance: true}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &BrowserProfile{SessionID: "pacing-variance-" + tt.name}
+			defer profilePacers.Delete(profile.SessionID)
+
+			p := getPacer(profile, &tt.config)
+			if p.speed < tt.min || p.speed > tt.max {
+				t.Errorf("speed = %v, want [%v, %v]", p.speed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestPacerCancelReleasesReservation(t *testing.T) {
+	profile := &BrowserProfile{SessionID: "pacing-cancel"}
+	defer profilePacers.Delete(profile.SessionID)
+	config := &PacingConfig{
+		ThinkTime:         UniformThinkTime{Min: time.Hour, Max: time.Hour},
+		NoProfileVariance: true,
+	}
+	p := getPacer(profile, config)
+
+	// Primeira navegação sai na hora
+	if err := p.wait(context.Background(), profile, config); err != nil {
+		t.Fatal(err)
+	}
+	p.done()
+	p.mu.Lock()
+	before := p.nextNav
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.wait(ctx, profile, config); err == nil {
+		t.Fatal("expected context error")
+	}
+
+	p.mu.Lock()
+	after := p.nextNav
+	p.mu.Unlock()
+	if !after.Equal(before) {
+		t.Errorf("nextNav = %v after cancel, want %v", after, before)
+	}
+}
+
+func TestPacerCancelKeepsLaterReservation(t *testing.T) {
+	profile := &BrowserProfile{SessionID: "pacing-stacked"}
+	defer profilePacers.Delete(profile.SessionID)
+	config := &PacingConfig{
+		ThinkTime:         UniformThinkTime{Min: time.Hour, Max: time.Hour},
+		NoProfileVariance: true,
+	}
+	p := getPacer(profile, config)
+	if err := p.wait(context.Background(), profile, config); err != nil {
+		t.Fatal(err)
+	}
+
+	reservedBeyond := func(d time.Duration) bool {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			p.mu.Lock()
+			ok := time.Until(p.nextNav) > d
+			p.mu.Unlock()
+			if ok {
+				return true
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return false
+	}
+
+	// Duas navegações na fila: +1h e +2h
+	first, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() { firstErr <- p.wait(first, profile, config) }()
+	if !reservedBeyond(30 * time.Minute) {
+		t.Fatal("first navigation did not reserve its slot")
+	}
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	secondErr := make(chan error, 1)
+	go func() { secondErr <- p.wait(second, profile, config) }()
+	if !reservedBeyond(90 * time.Minute) {
+		t.Fatal("second navigation did not reserve its slot")
+	}
+
+	// Cancelar a primeira não pode liberar o horário da segunda
+	cancelFirst()
+	if err := <-firstErr; err == nil {
+		t.Fatal("expected context error")
+	}
+	p.mu.Lock()
+	remaining := time.Until(p.nextNav)
+	p.mu.Unlock()
+	if remaining < 90*time.Minute {
+		t.Errorf("nextNav moved to %v from now, want the second reservation kept", remaining)
+	}
+
+	cancelSecond()
+	<-secondErr
+}
@@ -1,66 +1,212 @@
-package browserclient
-
-import (
-	"fmt"
-	"math/rand"
-	"sync"
-	"time"
-)
-
-var (
-	viewportSizes = [][2]int{
-		{1920, 1080}, {1366, 768}, {1536, 864}, {1440, 900},
-		{1280, 720}, {1600, 900}, {1680, 1050}, {2560, 1440},
-	}
-	colorDepths = []int{24, 32}
-	pixelRatios = []float32{1, 1.25, 1.5, 2}
-	languages = []string{
-		"pt-BR,pt;q=0.9,en;q=0.8",
-		"pt-BR,pt;q=0.9",
-		"en-US,en;q=0.9,pt-BR;q=0.8",
-		"pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7",
-	}
-	platforms = []string{"Win32", "Linux x86_64", "MacIntel"}
-	vendors   = []string{"Google Inc.", "Apple Computer, Inc.", ""}
-	userAgents = []string{
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:126.0) Gecko/20100101 Firefox/126.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:126.0) Gecko/20100101 Firefox/126.0",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15",
-	}
-)
-
-var threadProfiles sync.Map
-
-func generateBrowserProfile() *BrowserProfile {
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
-	
-	viewport := viewportSizes[r.Intn(len(viewportSizes))]
-	return &BrowserProfile{
-		ViewportWidth:  viewport[0],
-		ViewportHeight: viewport[1],
-		ColorDepth:     colorDepths[r.Intn(len(colorDepths))],
-		PixelRatio:     pixelRatios[r.Intn(len(pixelRatios))],
-		Language:       languages[r.Intn(len(languages))],
-		Platform:       platforms[r.Intn(len(platforms))],
-		Vendor:         vendors[r.Intn(len(vendors))],
-		TimezoneOffset: []int{-180, -120, -60, 0, 60, 120, 180}[r.Intn(7)],
-		SessionID:      fmt.Sprintf("%d-%d", time.Now().Unix(), r.Int63()),
-		CanvasNoise:    r.Float32(),
-		UserAgent:      userAgents[r.Intn(len(userAgents))],
-	}
-}
-
-func GetThreadProfile(threadID int) *BrowserProfile {
-	if profile, ok := threadProfiles.Load(threadID); ok {
-		return profile.(*BrowserProfile)
-	}
-	newProfile := generateBrowserProfile()
-	threadProfiles.Store(threadID, newProfile)
-	return newProfile
+package browserclient
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	viewportSizes = [][2]int{
+		{1920, 1080}, {1366, 768}, {1536, 864}, {1440, 900},
+		{1280, 720}, {1600, 900}, {1680, 1050}, {2560, 1440},
+	}
+	colorDepths = []int{24, 32}
+	pixelRatios = []float32{1, 1.25, 1.5, 2}
+	languages = []string{
+		"pt-BR,pt;q=0.9,en;q=0.8",
+		"pt-BR,pt;q=0.9",
+		"en-US,en;q=0.9,pt-BR;q=0.8",
+		"pt-BR,pt;q=0.9,en-US;q=0.8,en;q=0.7",
+	}
+	vendors   = []string{"Google Inc.", "Apple Computer, Inc.", ""}
+	userAgents = []catalogAgent{
+		{"Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"},
+		{"Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+		{"Chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"},
+		{"Chrome", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"},
+		{"Chrome", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"},
+		{"Edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 Edg/125.0.0.0"},
+		{"Edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0"},
+		{"Edge", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 Edg/125.0.0.0"},
+		{"Opera", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 OPR/111.0.0.0"},
+		{"Opera", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 OPR/110.0.0.0"},
+		// O Brave envia o UA do Chrome sem marca própria
+		{"Brave", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"},
+		{"Brave", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"},
+		{"Firefox", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:126.0) Gecko/20100101 Firefox/126.0"},
+		{"Firefox", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.5; rv:126.0) Gecko/20100101 Firefox/126.0"},
+		{"Safari", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15"},
+	}
+	// No Android o UA reduzido esconde o modelo ("K"); ele só aparece em
+	// Sec-Ch-Ua-Model
+	mobileUserAgents = []catalogAgent{
+		{"Chrome", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Mobile Safari/537.36"},
+		{"Chrome", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Mobile Safari/537.36"},
+		{"Safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"},
+		{"Safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.5 Mobile/15E148 Safari/604.1"},
+		{"Samsung", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/25.0 Chrome/121.0.0.0 Mobile Safari/537.36"},
+		{"Samsung", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/26.0 Chrome/122.0.0.0 Mobile Safari/537.36"},
+	}
+	// Viewport em pixels CSS, como o navegador reporta
+	mobileDevices = []mobileDevice{
+		{"Android", "Pixel 8", [2]int{412, 915}, 2.625},
+		{"Android", "Pixel 7", [2]int{412, 915}, 2.625},
+		{"Android", "SM-S911B", [2]int{360, 780}, 3},
+		{"Android", "SM-S928B", [2]int{384, 824}, 3.75},
+		{"Android", "SM-A546B", [2]int{384, 854}, 2.8125},
+		{"iOS", "iPhone 14", [2]int{390, 844}, 3},
+		{"iOS", "iPhone 14 Pro Max", [2]int{430, 932}, 3},
+		{"iOS", "iPhone 13", [2]int{390, 844}, 3},
+		{"iOS", "iPhone SE", [2]int{375, 667}, 2},
+	}
+)
+
+// DeviceClass separa os catálogos de perfis
+type DeviceClass string
+
+const (
+	DeviceDesktop DeviceClass = "desktop"
+	DeviceMobile  DeviceClass = "mobile"
+)
+
+// mobileDevice é um aparelho do catálogo móvel
+type mobileDevice struct {
+	os         string
+	model      string
+	viewport   [2]int
+	pixelRatio float32
+}
+
+// catalogAgent é um User-Agent do catálogo e a família que o envia
+type catalogAgent struct {
+	browser   string
+	userAgent string
+}
+
+var threadProfiles sync.Map
+
+func generateBrowserProfile() *BrowserProfile {
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+	return newBrowserProfile(r, userAgents, fmt.Sprintf("%d-%d", time.Now().Unix(), r.Int63()))
+}
+
+func newBrowserProfile(r *rand.Rand, agents []catalogAgent, sessionID string) *BrowserProfile {
+	agent := agents[r.Intn(len(agents))]
+	viewport := viewportSizes[r.Intn(len(viewportSizes))]
+	return &BrowserProfile{
+		ViewportWidth:  viewport[0],
+		ViewportHeight: viewport[1],
+		ColorDepth:     colorDepths[r.Intn(len(colorDepths))],
+		PixelRatio:     pixelRatios[r.Intn(len(pixelRatios))],
+		Language:       languages[r.Intn(len(languages))],
+		Platform:       navigatorPlatform(agent.userAgent),
+		Vendor:         vendors[r.Intn(len(vendors))],
+		TimezoneOffset: []int{-180, -120, -60, 0, 60, 120, 180}[r.Intn(7)],
+		SessionID:      sessionID,
+		CanvasNoise:    r.Float32(),
+		UserAgent:      agent.userAgent,
+		Browser:        agent.browser,
+	}
+}
+
+// navigatorPlatform é o navigator.platform do desktop correspondente ao UA
+// (o Windows 64 bits continua reportando "Win32")
+func navigatorPlatform(userAgent string) string {
+	switch uaPlatform(userAgent) {
+	case "macOS":
+		return "MacIntel"
+	case "Linux":
+		return "Linux x86_64"
+	}
+	return "Win32"
+}
+
+// newMobileProfile é o newBrowserProfile com tela, plataforma e modelo de um
+// aparelho compatível com o UA
+func newMobileProfile(r *rand.Rand, agents []catalogAgent, sessionID string) *BrowserProfile {
+	profile := newBrowserProfile(r, agents, sessionID)
+	platform := uaPlatform(profile.UserAgent)
+
+	var devices []mobileDevice
+	for _, d := range mobileDevices {
+		// O Samsung Internet só vem instalado nos Galaxy
+		if d.os == platform && (profile.Browser != "Samsung" || strings.HasPrefix(d.model, "SM-")) {
+			devices = append(devices, d)
+		}
+	}
+	device := devices[r.Intn(len(devices))]
+
+	profile.Mobile = true
+	profile.ViewportWidth = device.viewport[0]
+	profile.ViewportHeight = device.viewport[1]
+	profile.PixelRatio = device.pixelRatio
+	profile.ColorDepth = 24
+	if platform == "iOS" {
+		// Safari não expõe o modelo
+		profile.Platform = "iPhone"
+		profile.Vendor = "Apple Computer, Inc."
+	} else {
+		profile.Model = device.model
+		profile.Platform = "Linux armv81"
+		profile.Vendor = "Google Inc."
+	}
+	return profile
+}
+
+// GenerateProfile cria um perfil desktop do navegador family ("Chrome",
+// "Edge", "Opera", "Brave", "Firefox", "Safari"; vazio aceita qualquer um).
+// A mesma seed gera sempre o mesmo perfil; seed 0 usa o relógio.
+func GenerateProfile(family string, seed int64) (*BrowserProfile, error) {
+	return GenerateDeviceProfile(DeviceDesktop, family, seed)
+}
+
+// GenerateDeviceProfile é o GenerateProfile para uma classe de aparelho. No
+// celular as famílias são "Chrome" (Android), "Safari" (iOS) e "Samsung".
+func GenerateDeviceProfile(device DeviceClass, family string, seed int64) (*BrowserProfile, error) {
+	if device == "" {
+		device = DeviceDesktop
+	}
+	catalog := userAgents
+	generate := newBrowserProfile
+	switch device {
+	case DeviceDesktop:
+	case DeviceMobile:
+		catalog = mobileUserAgents
+		generate = newMobileProfile
+	default:
+		return nil, fmt.Errorf("unknown device class %q", device)
+	}
+
+	agents := catalog
+	if family != "" {
+		agents = nil
+		for _, agent := range catalog {
+			if strings.EqualFold(agent.browser, family) {
+				agents = append(agents, agent)
+			}
+		}
+		if len(agents) == 0 {
+			return nil, fmt.Errorf("unknown %s browser family %q", device, family)
+		}
+	}
+
+	if seed == 0 {
+		return generate(rand.New(rand.NewSource(time.Now().UnixNano())), agents,
+			fmt.Sprintf("%d-%d", time.Now().Unix(), rand.Int63())), nil
+	}
+	r := rand.New(rand.NewSource(seed))
+	return generate(r, agents, fmt.Sprintf("seed-%d-%d", seed, r.Int63())), nil
+}
+
+func GetThreadProfile(threadID int) *BrowserProfile {
+	if profile, ok := threadProfiles.Load(threadID); ok {
+		return profile.(*BrowserProfile)
+	}
+	newProfile := generateBrowserProfile()
+	threadProfiles.Store(threadID, newProfile)
+	return newProfile
 }
\ No newline at end of file
@@ -0,0 +1,692 @@
+package browserclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	defaultProxyMaxFailures   = 3
+	defaultProxyBlockDuration = 5 * time.Minute
+	proxyConnectTimeout       = 15 * time.Second
+)
+
+// ErrNoProxyAvailable indica que todos os proxies do pool estão bloqueados
+var ErrNoProxyAvailable = errors.New("no healthy proxy available")
+
+// ProxyPolicy define como o pool escolhe o proxy de cada conexão
+type ProxyPolicy int
+
+const (
+	ProxyRoundRobin ProxyPolicy = iota
+	ProxyRandom
+	// ProxyLeastFailures escolhe o proxy com menos falhas acumuladas
+	ProxyLeastFailures
+	// ProxyStickyPerHost mantém o mesmo proxy para cada host de destino
+	ProxyStickyPerHost
+)
+
+// ProxyPool é um conjunto de proxies consultado a cada nova conexão.
+// Pode ser compartilhado entre vários BrowserClient.
+type ProxyPool struct {
+	Policy ProxyPolicy
+	// Falhas consecutivas de conexão até o proxy ser bloqueado
+	MaxFailures int
+	// Tempo que um proxy bloqueado fica fora de uso antes de ser retestado
+	BlockDuration time.Duration
+	// Status HTTP que indicam bloqueio do IP de saída (ex.: 403, 429)
+	BlockStatusCodes []int
+	// Destino usado no health check (host:porta); vazio testa só a conexão TCP
+	HealthCheckTarget string
+
+	mu      sync.Mutex
+	entries []*proxyEntry
+	next    int
+	sticky  map[string]*proxyEntry
+	rng     *rand.Rand
+
+	stopOnce sync.Once
+	stop     chan struct{}
+
+	// Config do primeiro cliente que usou o pool; o health check disca com
+	// o mesmo resolver, bind local e timeouts
+	checkConfig  *ClientConfig
+	checkProfile *BrowserProfile
+}
+
+type proxyEntry struct {
+	url  *url.URL
+	name string
+
+	consecutiveFailures int
+	totalFailures       int
+	successes           int
+	blockedUntil        time.Time
+
+	auth proxyAuthState
+}
+
+// ProxyStatus é um retrato do estado de um proxy do pool
+type ProxyStatus struct {
+	URL          string
+	Failures     int
+	Successes    int
+	Blocked      bool
+	BlockedUntil time.Time
+}
+
+// NewProxyPool cria um pool com as URLs informadas (http, https, socks5)
+func NewProxyPool(urls []string, policy ProxyPolicy) (*ProxyPool, error) {
+	pool := &ProxyPool{
+		Policy: policy,
+		sticky: make(map[string]*proxyEntry),
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		pool.entries = append(pool.entries, &proxyEntry{url: u, name: u.Redacted()})
+	}
+	if len(pool.entries) == 0 {
+		return nil, errors.New("proxy pool is empty")
+	}
+	return pool, nil
+}
+
+// newSingleProxyPool cria o pool de um proxy fixo, que nunca é bloqueado
+func newSingleProxyPool(proxyURL string) (*ProxyPool, error) {
+	pool, err := NewProxyPool([]string{proxyURL}, ProxyRoundRobin)
+	if err != nil {
+		return nil, err
+	}
+	pool.MaxFailures = math.MaxInt32
+	return pool, nil
+}
+
+// pick escolhe o proxy para uma nova conexão com o host
+func (p *ProxyPool) pick(host string) (*proxyEntry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var healthy []*proxyEntry
+	for _, e := range p.entries {
+		if now.After(e.blockedUntil) {
+			healthy = append(healthy, e)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, ErrNoProxyAvailable
+	}
+
+	switch p.Policy {
+	case ProxyRandom:
+		return healthy[p.rng.Intn(len(healthy))], nil
+
+	case ProxyLeastFailures:
+		best := healthy[0]
+		for _, e := range healthy[1:] {
+			if e.totalFailures < best.totalFailures {
+				best = e
+			}
+		}
+		return best, nil
+
+	case ProxyStickyPerHost:
+		if p.sticky == nil {
+			p.sticky = make(map[string]*proxyEntry)
+		}
+		if e, ok := p.sticky[host]; ok && now.After(e.blockedUntil) {
+			return e, nil
+		}
+		e := p.roundRobin(now)
+		p.sticky[host] = e
+		return e, nil
+
+	default:
+		return p.roundRobin(now), nil
+	}
+}
+
+// roundRobin avança até o próximo proxy saudável (chamado com mu travado)
+func (p *ProxyPool) roundRobin(now time.Time) *proxyEntry {
+	for i := 0; i < len(p.entries); i++ {
+		e := p.entries[(p.next+i)%len(p.entries)]
+		if now.After(e.blockedUntil) {
+			p.next = (p.next + i + 1) % len(p.entries)
+			return e
+		}
+	}
+	return nil
+}
+
+func (p *ProxyPool) findByName(name string) *proxyEntry {
+	for _, e := range p.entries {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// findByAddr localiza o proxy pelo host:porta discado
+func (p *ProxyPool) findByAddr(addr string) *proxyEntry {
+	for _, e := range p.entries {
+		if proxyAddr(e.url) == addr {
+			return e
+		}
+	}
+	return nil
+}
+
+func (p *ProxyPool) markFailed(e *proxyEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.consecutiveFailures++
+	e.totalFailures++
+
+	maxFailures := p.MaxFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultProxyMaxFailures
+	}
+	if e.consecutiveFailures >= maxFailures {
+		e.blockedUntil = time.Now().Add(p.blockDuration())
+	}
+}
+
+func (p *ProxyPool) markSuccess(e *proxyEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.consecutiveFailures = 0
+	e.successes++
+}
+
+func (p *ProxyPool) markBlocked(e *proxyEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.totalFailures++
+	e.blockedUntil = time.Now().Add(p.blockDuration())
+}
+
+// MarkBlocked tira um proxy de uso (pela URL, com ou sem senha)
+func (p *ProxyPool) MarkBlocked(proxyURL string) {
+	if e := p.lookup(proxyURL); e != nil {
+		p.markBlocked(e)
+	}
+}
+
+// MarkHealthy devolve um proxy ao uso e zera as falhas consecutivas
+func (p *ProxyPool) MarkHealthy(proxyURL string) {
+	e := p.lookup(proxyURL)
+	if e == nil {
+		return
+	}
+	p.mu.Lock()
+	e.consecutiveFailures = 0
+	e.blockedUntil = time.Time{}
+	p.mu.Unlock()
+}
+
+func (p *ProxyPool) lookup(proxyURL string) *proxyEntry {
+	if e := p.findByName(proxyURL); e != nil {
+		return e
+	}
+	if u, err := url.Parse(proxyURL); err == nil {
+		return p.findByName(u.Redacted())
+	}
+	return nil
+}
+
+// Status retorna o estado atual de todos os proxies
+func (p *ProxyPool) Status() []ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := make([]ProxyStatus, len(p.entries))
+	for i, e := range p.entries {
+		status[i] = ProxyStatus{
+			URL:          e.name,
+			Failures:     e.totalFailures,
+			Successes:    e.successes,
+			Blocked:      now.Before(e.blockedUntil),
+			BlockedUntil: e.blockedUntil,
+		}
+	}
+	return status
+}
+
+func (p *ProxyPool) blockDuration() time.Duration {
+	if p.BlockDuration > 0 {
+		return p.BlockDuration
+	}
+	return defaultProxyBlockDuration
+}
+
+func (p *ProxyPool) isBlockStatus(code int) bool {
+	for _, c := range p.BlockStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// StartHealthCheck retesta periodicamente os proxies bloqueados em segundo
+// plano, liberando os que voltarem a responder. Os testes usam a configuração
+// de rede (Resolver, LocalAddr/Interface/LocalAddrPool, DualStack, DialTimeout)
+// do primeiro BrowserClient criado com o pool.
+func (p *ProxyPool) StartHealthCheck(interval time.Duration) {
+	p.mu.Lock()
+	if p.stop != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stop = make(chan struct{})
+	stop := p.stop
+	p.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.checkBlocked()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopHealthCheck encerra o health check em segundo plano
+func (p *ProxyPool) StopHealthCheck() {
+	p.mu.Lock()
+	stop := p.stop
+	p.mu.Unlock()
+	if stop != nil {
+		p.stopOnce.Do(func() { close(stop) })
+	}
+}
+
+func (p *ProxyPool) checkBlocked() {
+	p.mu.Lock()
+	now := time.Now()
+	var blocked []*proxyEntry
+	for _, e := range p.entries {
+		if now.Before(e.blockedUntil) {
+			blocked = append(blocked, e)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, e := range blocked {
+		ctx, cancel := context.WithTimeout(context.Background(), proxyConnectTimeout)
+		err := p.check(ctx, e)
+		cancel()
+		if err == nil {
+			p.MarkHealthy(e.name)
+		}
+	}
+}
+
+// attach registra a config do cliente dono do pool para o health check;
+// com o pool compartilhado, vale a do primeiro cliente
+func (p *ProxyPool) attach(config *ClientConfig, profile *BrowserProfile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.checkConfig == nil {
+		p.checkConfig = config
+		p.checkProfile = profile
+	}
+}
+
+func (p *ProxyPool) check(ctx context.Context, e *proxyEntry) error {
+	p.mu.Lock()
+	config, profile := p.checkConfig, p.checkProfile
+	p.mu.Unlock()
+	if config == nil {
+		config, profile = &ClientConfig{}, &BrowserProfile{}
+	}
+
+	if p.HealthCheckTarget == "" {
+		conn, _, err := dialTimed(ctx, "tcp", proxyAddr(e.url), config, profile)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	conn, _, err := dialViaProxy(ctx, "tcp", p.HealthCheckTarget, e, config, profile)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// proxyAddr retorna o host:porta do proxy com a porta padrão do esquema
+func proxyAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// dialThroughPool abre a conexão TCP até addr, via proxy do pool quando houver
+func dialThroughPool(ctx context.Context, network, addr string, config *ClientConfig, profile *BrowserProfile, proxies *ProxyPool) (net.Conn, connTiming, string, error) {
+	if proxies == nil {
+		conn, timing, err := dialTimed(ctx, network, addr, config, profile)
+		return conn, timing, "", err
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+
+	// Falha ao conectar no proxy passa para o próximo saudável do pool
+	var lastErr error
+	for attempt := 0; attempt < len(proxies.entries); attempt++ {
+		entry, err := proxies.pick(host)
+		if err != nil {
+			if lastErr != nil {
+				return nil, connTiming{}, "", lastErr
+			}
+			return nil, connTiming{}, "", err
+		}
+
+		conn, timing, err := dialViaProxy(ctx, network, addr, entry, config, profile)
+		if err == nil {
+			proxies.markSuccess(entry)
+			return conn, timing, entry.name, nil
+		}
+		lastErr = fmt.Errorf("proxy %s: %w", entry.name, err)
+		if ctx.Err() != nil {
+			break
+		}
+		proxies.markFailed(entry)
+	}
+	return nil, connTiming{}, "", lastErr
+}
+
+type forwardProxyKey struct{}
+
+// forwardProxyURL é o http.Transport.Proxy: devolve o proxy escolhido para a
+// requisição, sem credenciais (o Proxy-Authorization é montado por nós)
+func forwardProxyURL(req *http.Request) (*url.URL, error) {
+	entry, ok := req.Context().Value(forwardProxyKey{}).(*proxyEntry)
+	if !ok {
+		return nil, nil
+	}
+	u := *entry.url
+	u.User = nil
+	return &u, nil
+}
+
+// roundTripForwardProxy envia uma requisição HTTP simples pelo proxy,
+// respondendo aos desafios 407 (Basic, Digest e NTLM)
+func (t *browserTransport) roundTripForwardProxy(req *http.Request) (*http.Response, error) {
+	entry, err := t.proxies.pick(req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	creds := credentialsFromURL(entry.url)
+	uri := req.URL.String()
+	authorization := entry.auth.preemptive(creds, req.Method, uri)
+	ctx := context.WithValue(req.Context(), forwardProxyKey{}, entry)
+
+	// O NTLM autentica a conexão, não a requisição: o handshake e a
+	// requisição final vão por um transport exclusivo de uma conexão só
+	var pinned *http.Transport
+	rt := http.RoundTripper(t.h1)
+
+	for round := 0; ; round++ {
+		if pinned == nil && strings.HasPrefix(authorization, "NTLM ") {
+			pinned = t.h1.Clone()
+			pinned.MaxConnsPerHost = 1
+			rt = pinned
+		}
+
+		outReq := req.Clone(ctx)
+		if authorization != "" {
+			outReq.Header.Set("Proxy-Authorization", authorization)
+		}
+		if round > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				closeIdle(pinned)
+				return nil, fmt.Errorf("%w: request body cannot be replayed", errProxyAuthFailed)
+			}
+			if outReq.Body, err = req.GetBody(); err != nil {
+				closeIdle(pinned)
+				return nil, err
+			}
+		}
+
+		resp, err := rt.RoundTrip(outReq)
+		if err != nil || resp.StatusCode != http.StatusProxyAuthRequired || creds == nil || round+1 >= maxProxyAuthRounds {
+			if pinned != nil {
+				if err != nil {
+					closeIdle(pinned)
+				} else {
+					resp.Body = &pinnedBody{ReadCloser: resp.Body, transport: pinned}
+				}
+			}
+			return resp, err
+		}
+
+		next, err := entry.auth.respond(parseAuthChallenges(resp.Header.Values("Proxy-Authenticate")), creds, req.Method, uri, authorization)
+		if err != nil {
+			// Sem como autenticar: o 407 segue para quem chamou
+			if pinned != nil {
+				resp.Body = &pinnedBody{ReadCloser: resp.Body, transport: pinned}
+			}
+			return resp, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if resp.Close && pinned != nil && strings.HasPrefix(next, "NTLM ") {
+			closeIdle(pinned)
+			return nil, fmt.Errorf("%w: proxy closed the connection during NTLM handshake", errProxyAuthFailed)
+		}
+		authorization = next
+	}
+}
+
+// pinnedBody fecha a conexão exclusiva do NTLM junto com a resposta
+type pinnedBody struct {
+	io.ReadCloser
+	transport *http.Transport
+}
+
+func (b *pinnedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.transport.CloseIdleConnections()
+	return err
+}
+
+func closeIdle(t *http.Transport) {
+	if t != nil {
+		t.CloseIdleConnections()
+	}
+}
+
+// dialViaProxy abre um túnel até addr pelo proxy (CONNECT ou SOCKS5)
+func dialViaProxy(ctx context.Context, network, addr string, entry *proxyEntry, config *ClientConfig, profile *BrowserProfile) (net.Conn, connTiming, error) {
+	switch entry.url.Scheme {
+	case "socks5", "socks5h":
+		return dialSOCKS5(ctx, network, addr, entry, config, profile)
+	}
+
+	creds := credentialsFromURL(entry.url)
+	authorization := entry.auth.preemptive(creds, http.MethodConnect, addr)
+
+	var conn net.Conn
+	var reader *bufio.Reader
+	var timing connTiming
+
+	// Cada 407 traz um desafio; NTLM exige as respostas na mesma conexão
+	for round := 0; round < maxProxyAuthRounds; round++ {
+		if conn == nil {
+			var err error
+			conn, timing, err = dialProxyConn(ctx, network, entry, config, profile)
+			if err != nil {
+				return nil, timing, err
+			}
+			reader = bufio.NewReader(conn)
+		}
+
+		resp, err := sendConnect(ctx, conn, reader, addr, profile, authorization)
+		if err != nil {
+			conn.Close()
+			return nil, timing, err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			resp.Body.Close()
+			return conn, timing, nil
+		}
+		if resp.StatusCode != http.StatusProxyAuthRequired {
+			resp.Body.Close()
+			conn.Close()
+			return nil, timing, fmt.Errorf("proxy CONNECT returned %s", resp.Status)
+		}
+
+		next, err := entry.auth.respond(parseAuthChallenges(resp.Header.Values("Proxy-Authenticate")), creds, http.MethodConnect, addr, authorization)
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if err != nil {
+			conn.Close()
+			return nil, timing, err
+		}
+
+		if resp.Close {
+			conn.Close()
+			conn = nil
+			if strings.HasPrefix(authorization, "NTLM ") && strings.HasPrefix(next, "NTLM ") {
+				return nil, timing, fmt.Errorf("%w: proxy closed the connection during NTLM handshake", errProxyAuthFailed)
+			}
+		}
+		authorization = next
+	}
+
+	if conn != nil {
+		conn.Close()
+	}
+	return nil, timing, errProxyAuthFailed
+}
+
+// dialProxyConn conecta ao proxy; proxies HTTPS recebem TLS padrão e o
+// fingerprint uTLS vale só para o destino, dentro do túnel
+func dialProxyConn(ctx context.Context, network string, entry *proxyEntry, config *ClientConfig, profile *BrowserProfile) (net.Conn, connTiming, error) {
+	conn, timing, err := dialTimed(ctx, network, proxyAddr(entry.url), config, profile)
+	if err != nil {
+		return nil, timing, err
+	}
+
+	if entry.url.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: entry.url.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, timing, fmt.Errorf("proxy TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+	return conn, timing, nil
+}
+
+// sendConnect envia um CONNECT e lê a resposta do proxy
+func sendConnect(ctx context.Context, conn net.Conn, reader *bufio.Reader, addr string, profile *BrowserProfile, authorization string) (*http.Response, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	req.Header.Set("User-Agent", profile.UserAgent)
+	if authorization != "" {
+		req.Header.Set("Proxy-Authorization", authorization)
+	}
+
+	deadline := time.Now().Add(proxyConnectTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	return resp, nil
+}
+
+func dialSOCKS5(ctx context.Context, network, addr string, entry *proxyEntry, config *ClientConfig, profile *BrowserProfile) (net.Conn, connTiming, error) {
+	var timing connTiming
+	forward := &timedForwardDialer{config: config, profile: profile, timing: &timing}
+
+	dialer, err := proxy.FromURL(entry.url, forward)
+	if err != nil {
+		return nil, timing, err
+	}
+	ctxDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, timing, errors.New("SOCKS5 dialer does not support context")
+	}
+
+	conn, err := ctxDialer.DialContext(ctx, network, addr)
+	return conn, timing, err
+}
+
+// timedForwardDialer liga o dialer SOCKS5 ao dialTimed (resolver, Happy
+// Eyeballs e IPs de origem configurados)
+type timedForwardDialer struct {
+	config  *ClientConfig
+	profile *BrowserProfile
+	timing  *connTiming
+}
+
+func (d *timedForwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *timedForwardDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, timing, err := dialTimed(ctx, network, addr, d.config, d.profile)
+	*d.timing = timing
+	return conn, err
+}
@@ -0,0 +1,53 @@
+package browserclient
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProxyPoolCheckUsesClientConfig(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	pool, err := NewProxyPool([]string{"http://proxy.invalid:" + port}, ProxyRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Sem a config do cliente, proxy.invalid não resolve
+	if err := pool.check(ctx, pool.entries[0]); err == nil {
+		t.Fatal("check without client config should fail to resolve proxy.invalid")
+	}
+
+	config := &ClientConfig{
+		Resolver: &StaticResolver{Hosts: map[string][]net.IP{"proxy.invalid": {net.ParseIP("127.0.0.1")}}},
+	}
+	pool.attach(config, &BrowserProfile{})
+	if err := pool.check(ctx, pool.entries[0]); err != nil {
+		t.Fatalf("check with client resolver: %v", err)
+	}
+
+	// Um segundo cliente no mesmo pool não troca a config do health check
+	pool.attach(&ClientConfig{}, &BrowserProfile{})
+	if err := pool.check(ctx, pool.entries[0]); err != nil {
+		t.Fatalf("check after second attach: %v", err)
+	}
+}
@@ -0,0 +1,499 @@
+package browserclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// Máximo de idas e voltas com o proxy até desistir da autenticação
+const maxProxyAuthRounds = 4
+
+var errProxyAuthFailed = errors.New("proxy authentication failed")
+
+// proxyCredentials são as credenciais já decodificadas da URL do proxy.
+// No NTLM o usuário pode vir como "DOMINIO\usuario".
+type proxyCredentials struct {
+	user string
+	pass string
+}
+
+// credentialsFromURL decodifica usuário e senha (url.User.String() mantém o
+// escape, o que quebrava senhas com caracteres especiais)
+func credentialsFromURL(u *url.URL) *proxyCredentials {
+	if u == nil || u.User == nil {
+		return nil
+	}
+	pass, _ := u.User.Password()
+	return &proxyCredentials{user: u.User.Username(), pass: pass}
+}
+
+func (c *proxyCredentials) basic() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.user+":"+c.pass))
+}
+
+// ntlmUser separa domínio e usuário de "DOMINIO\usuario"
+func (c *proxyCredentials) ntlmUser() (user, domain string) {
+	if i := strings.IndexByte(c.user, '\\'); i >= 0 {
+		return c.user[i+1:], c.user[:i]
+	}
+	return c.user, ""
+}
+
+// authChallenge é um desafio Proxy-Authenticate
+type authChallenge struct {
+	scheme string // em minúsculas
+	params map[string]string
+	token  string // token NTLM (base64), se houver
+}
+
+// parseAuthChallenges lê os headers Proxy-Authenticate (um desafio por header)
+func parseAuthChallenges(values []string) []authChallenge {
+	var challenges []authChallenge
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		scheme, rest, _ := strings.Cut(v, " ")
+		ch := authChallenge{scheme: strings.ToLower(scheme), params: make(map[string]string)}
+		rest = strings.TrimSpace(rest)
+		switch ch.scheme {
+		case "ntlm", "negotiate":
+			ch.token = rest
+		default:
+			ch.params = parseAuthParams(rest)
+		}
+		challenges = append(challenges, ch)
+	}
+	return challenges
+}
+
+// parseAuthParams lê pares chave=valor, com valores opcionalmente entre aspas
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+	return params
+}
+
+func findChallenge(challenges []authChallenge, scheme string) *authChallenge {
+	for i := range challenges {
+		if challenges[i].scheme == scheme {
+			return &challenges[i]
+		}
+	}
+	return nil
+}
+
+// proxyAuthState guarda o esquema aprendido de um proxy para que as próximas
+// conexões já autentiquem na primeira tentativa
+type proxyAuthState struct {
+	mu     sync.Mutex
+	scheme string
+	digest *authChallenge
+	nc     uint32
+}
+
+// preemptive retorna o Proxy-Authorization da primeira tentativa, só com um
+// esquema já aprendido num 407 (Digest com o último nonce, início do NTLM ou
+// Basic). Como nos navegadores, um proxy novo não recebe credenciais antes
+// de dizer qual esquema aceita.
+func (s *proxyAuthState) preemptive(creds *proxyCredentials, method, uri string) string {
+	if creds == nil {
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.scheme {
+	case "ntlm":
+		return "NTLM " + base64.StdEncoding.EncodeToString(ntlmNegotiate())
+	case "digest":
+		if s.digest != nil {
+			s.nc++
+			if auth, err := digestAuthorization(s.digest, creds, method, uri, s.nc); err == nil {
+				return auth
+			}
+		}
+	case "basic":
+		return creds.basic()
+	}
+	return ""
+}
+
+// respond responde a um 407 seguindo a preferência dos navegadores
+// (NTLM, Digest, Basic). prev é o header enviado na tentativa anterior.
+func (s *proxyAuthState) respond(challenges []authChallenge, creds *proxyCredentials, method, uri, prev string) (string, error) {
+	if creds == nil {
+		return "", fmt.Errorf("%w: proxy requires credentials", errProxyAuthFailed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prevScheme := strings.ToLower(strings.SplitN(prev, " ", 2)[0])
+
+	if ch := findChallenge(challenges, "ntlm"); ch != nil {
+		s.scheme = "ntlm"
+		if ch.token == "" {
+			if prevScheme == "ntlm" && prev != "NTLM "+base64.StdEncoding.EncodeToString(ntlmNegotiate()) {
+				return "", fmt.Errorf("%w: NTLM credentials rejected", errProxyAuthFailed)
+			}
+			return "NTLM " + base64.StdEncoding.EncodeToString(ntlmNegotiate()), nil
+		}
+		challenge, err := base64.StdEncoding.DecodeString(ch.token)
+		if err != nil {
+			return "", fmt.Errorf("invalid NTLM challenge: %w", err)
+		}
+		msg, err := ntlmAuthenticate(challenge, creds)
+		if err != nil {
+			return "", err
+		}
+		return "NTLM " + base64.StdEncoding.EncodeToString(msg), nil
+	}
+
+	if ch := findChallenge(challenges, "digest"); ch != nil {
+		// Repetir Digest só faz sentido se o nonce expirou (stale=true)
+		if prevScheme == "digest" && !strings.EqualFold(ch.params["stale"], "true") {
+			return "", fmt.Errorf("%w: digest credentials rejected", errProxyAuthFailed)
+		}
+		s.scheme = "digest"
+		s.digest = ch
+		s.nc = 1
+		return digestAuthorization(ch, creds, method, uri, s.nc)
+	}
+
+	if findChallenge(challenges, "basic") != nil {
+		if prevScheme == "basic" {
+			return "", fmt.Errorf("%w: basic credentials rejected", errProxyAuthFailed)
+		}
+		s.scheme = "basic"
+		return creds.basic(), nil
+	}
+
+	return "", fmt.Errorf("%w: no supported scheme in %v", errProxyAuthFailed, challenges)
+}
+
+// digestAuthorization calcula o header Digest (RFC 7616, com fallback RFC 2069)
+func digestAuthorization(ch *authChallenge, creds *proxyCredentials, method, uri string, nc uint32) (string, error) {
+	algorithm := ch.params["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	realm, nonce := ch.params["realm"], ch.params["nonce"]
+	cnonce := randomHex(8)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(creds.user + ":" + realm + ":" + creds.pass)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	qop := ""
+	for _, q := range strings.Split(ch.params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, ncValue, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		creds.user, realm, nonce, uri, algorithm, response)
+	if opaque, ok := ch.params["opaque"]; ok {
+		fmt.Fprintf(&b, `, opaque="%s"`, opaque)
+	}
+	if qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, qop, ncValue, cnonce)
+	}
+	return b.String(), nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NTLM (MS-NLMP) com respostas NTLMv2
+const (
+	ntlmNegotiateUnicode         = 0x00000001
+	ntlmNegotiateOEM             = 0x00000002
+	ntlmRequestTarget            = 0x00000004
+	ntlmNegotiateNTLM            = 0x00000200
+	ntlmNegotiateAlwaysSign      = 0x00008000
+	ntlmNegotiateExtendedSession = 0x00080000
+	ntlmNegotiateTargetInfo      = 0x00800000
+	ntlmNegotiateVersion         = 0x02000000
+	ntlmNegotiate128             = 0x20000000
+	ntlmNegotiate56              = 0x80000000
+
+	ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmNegotiateOEM | ntlmRequestTarget |
+		ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSession |
+		ntlmNegotiateVersion | ntlmNegotiate128 | ntlmNegotiate56
+
+	ntlmAvEOL       = 0
+	ntlmAvFlags     = 6
+	ntlmAvTimestamp = 7
+
+	// MsvAvFlags: a AUTHENTICATE leva MIC
+	ntlmAvFlagMICPresent = 0x00000002
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmVersion anuncia Windows 10 (build 19041), revisão NTLMSSP 15
+var ntlmVersion = []byte{10, 0, 0x61, 0x4a, 0, 0, 0, 0x0f}
+
+// ntlmNegotiate monta a mensagem NEGOTIATE (tipo 1)
+func ntlmNegotiate() []byte {
+	msg := make([]byte, 40)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	// Domínio e workstation vazios (security buffers zerados)
+	copy(msg[32:], ntlmVersion)
+	return msg
+}
+
+// ntlmAuthenticate responde à mensagem CHALLENGE (tipo 2) com a AUTHENTICATE (tipo 3)
+func ntlmAuthenticate(challenge []byte, creds *proxyCredentials) ([]byte, error) {
+	if len(challenge) < 32 || !bytes.Equal(challenge[:8], ntlmSignature) || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge message")
+	}
+	flags := binary.LittleEndian.Uint32(challenge[20:])
+	serverChallenge := challenge[24:32]
+
+	var targetInfo []byte
+	if flags&ntlmNegotiateTargetInfo != 0 && len(challenge) >= 48 {
+		length := int(binary.LittleEndian.Uint16(challenge[40:]))
+		offset := int(binary.LittleEndian.Uint32(challenge[44:]))
+		if offset+length > len(challenge) {
+			return nil, errors.New("invalid NTLM target info")
+		}
+		targetInfo = challenge[offset : offset+length]
+	}
+
+	user, domain := creds.ntlmUser()
+
+	responseKey := ntowfv2(creds.pass, user, domain)
+
+	clientChallenge := make([]byte, 8)
+	rand.Read(clientChallenge)
+
+	// Com MsvAvTimestamp do servidor o cliente não manda resposta LMv2 e
+	// protege as três mensagens com o MIC (MS-NLMP 3.1.5.1.2)
+	timestamp, serverTimestamp := ntlmTimestamp(targetInfo)
+	if serverTimestamp {
+		targetInfo = ntlmTargetInfoWithMIC(targetInfo)
+	}
+
+	ntProof, ntResponse := ntlmV2Response(responseKey, serverChallenge, clientChallenge, timestamp, targetInfo)
+	lmResponse := make([]byte, 24)
+	if !serverTimestamp {
+		lmResponse = append(hmacMD5(responseKey, append(append([]byte{}, serverChallenge...), clientChallenge...)), clientChallenge...)
+	}
+
+	domainBytes := utf16le(domain)
+	userBytes := utf16le(user)
+	workstation := utf16le("")
+
+	// Cabeçalho fixo + Version (8) + MIC (16)
+	const (
+		micOffset = 72
+		headerLen = micOffset + 16
+	)
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+
+	payload := []struct {
+		field int
+		data  []byte
+	}{
+		{12, lmResponse},
+		{20, ntResponse},
+		{28, domainBytes},
+		{36, userBytes},
+		{44, workstation},
+		{52, nil}, // EncryptedRandomSessionKey (sem KEY_EXCH)
+	}
+	for _, p := range payload {
+		binary.LittleEndian.PutUint16(msg[p.field:], uint16(len(p.data)))
+		binary.LittleEndian.PutUint16(msg[p.field+2:], uint16(len(p.data)))
+		binary.LittleEndian.PutUint32(msg[p.field+4:], uint32(len(msg)))
+		msg = append(msg, p.data...)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags&ntlmNegotiateFlags|ntlmNegotiateUnicode)
+	copy(msg[64:], ntlmVersion)
+
+	if serverTimestamp {
+		// Sem KEY_EXCH, ExportedSessionKey = SessionBaseKey = HMAC_MD5(ResponseKeyNT, NTProofStr)
+		sessionKey := hmacMD5(responseKey, ntProof)
+		mac := hmac.New(md5.New, sessionKey)
+		mac.Write(ntlmNegotiate())
+		mac.Write(challenge)
+		mac.Write(msg)
+		copy(msg[micOffset:], mac.Sum(nil))
+	}
+	return msg, nil
+}
+
+// ntowfv2 = HMAC_MD5(MD4(UNICODE(senha)), UNICODE(MAIÚSCULAS(usuário) + domínio))
+func ntowfv2(pass, user, domain string) []byte {
+	md4Hash := md4.New()
+	md4Hash.Write(utf16le(pass))
+	return hmacMD5(md4Hash.Sum(nil), utf16le(strings.ToUpper(user)+domain))
+}
+
+// ntlmV2Response calcula o NTProofStr e a NtChallengeResponse completa
+func ntlmV2Response(responseKey, serverChallenge, clientChallenge, timestamp, targetInfo []byte) (ntProof, ntResponse []byte) {
+	temp := make([]byte, 0, 28+len(targetInfo)+4)
+	temp = append(temp, 1, 1, 0, 0, 0, 0, 0, 0)
+	temp = append(temp, timestamp...)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, targetInfo...)
+	temp = append(temp, 0, 0, 0, 0)
+
+	ntProof = hmacMD5(responseKey, append(append([]byte{}, serverChallenge...), temp...))
+	return ntProof, append(append([]byte{}, ntProof...), temp...)
+}
+
+// ntlmTargetInfoWithMIC devolve uma cópia do target info com o bit de MIC
+// ligado no MsvAvFlags (acrescentando o par se o servidor não mandou)
+func ntlmTargetInfoWithMIC(targetInfo []byte) []byte {
+	out := make([]byte, 0, len(targetInfo)+8)
+	hasFlags := false
+	for av := targetInfo; len(av) >= 4; {
+		id := binary.LittleEndian.Uint16(av)
+		length := int(binary.LittleEndian.Uint16(av[2:]))
+		if id == ntlmAvEOL || 4+length > len(av) {
+			break
+		}
+		pair := append([]byte{}, av[:4+length]...)
+		if id == ntlmAvFlags && length == 4 {
+			binary.LittleEndian.PutUint32(pair[4:], binary.LittleEndian.Uint32(pair[4:])|ntlmAvFlagMICPresent)
+			hasFlags = true
+		}
+		out = append(out, pair...)
+		av = av[4+length:]
+	}
+	if !hasFlags {
+		pair := make([]byte, 8)
+		binary.LittleEndian.PutUint16(pair, ntlmAvFlags)
+		binary.LittleEndian.PutUint16(pair[2:], 4)
+		binary.LittleEndian.PutUint32(pair[4:], ntlmAvFlagMICPresent)
+		out = append(out, pair...)
+	}
+	return append(out, 0, 0, 0, 0) // MsvAvEOL
+}
+
+// ntlmTimestamp usa o MsvAvTimestamp do servidor ou o horário atual (FILETIME);
+// o bool indica se veio do servidor
+func ntlmTimestamp(targetInfo []byte) ([]byte, bool) {
+	for av := targetInfo; len(av) >= 4; {
+		id := binary.LittleEndian.Uint16(av)
+		length := int(binary.LittleEndian.Uint16(av[2:]))
+		if id == ntlmAvEOL || 4+length > len(av) {
+			break
+		}
+		if id == ntlmAvTimestamp && length == 8 {
+			return append([]byte{}, av[4:12]...), true
+		}
+		av = av[4+length:]
+	}
+
+	// Intervalos de 100ns desde 1601-01-01
+	ft := uint64(time.Now().UnixNano()/100) + 116444736000000000
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, ft)
+	return b, false
+}
+
+func hmacMD5(key, data []byte) []byte {
+	mac := hmac.New(md5.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func utf16le(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return b
+}
@@ -0,0 +1,236 @@
+package browserclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Vetores de MS-NLMP 4.2.4 (NTLMv2)
+func TestNTLMv2Vectors(t *testing.T) {
+	responseKey := ntowfv2("Password", "User", "Domain")
+	if want := mustHex(t, "0c868a403bfd7a93a3001ef22ef02e3f"); !bytes.Equal(responseKey, want) {
+		t.Fatalf("NTOWFv2 = %x, want %x", responseKey, want)
+	}
+
+	serverChallenge := mustHex(t, "0123456789abcdef")
+	clientChallenge := mustHex(t, "aaaaaaaaaaaaaaaa")
+	timestamp := make([]byte, 8)
+	targetInfo := mustHex(t, "02000c00 44006f006d00610069006e00 01000c00 530065007200760065007200 00000000")
+
+	ntProof, ntResponse := ntlmV2Response(responseKey, serverChallenge, clientChallenge, timestamp, targetInfo)
+	if want := mustHex(t, "68cd0ab851e51c96aabc927bebef6a1c"); !bytes.Equal(ntProof, want) {
+		t.Errorf("NTProofStr = %x, want %x", ntProof, want)
+	}
+	if !bytes.HasPrefix(ntResponse, ntProof) || !bytes.HasSuffix(ntResponse, append(targetInfo, 0, 0, 0, 0)) {
+		t.Errorf("NtChallengeResponse layout = %x", ntResponse)
+	}
+	if got, want := hmacMD5(responseKey, ntProof), mustHex(t, "8de40ccadbc14a82f15cb0ad0de95ca3"); !bytes.Equal(got, want) {
+		t.Errorf("SessionBaseKey = %x, want %x", got, want)
+	}
+}
+
+// ntlmTestChallenge monta uma CHALLENGE com o target info dado
+func ntlmTestChallenge(targetInfo []byte) []byte {
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmNegotiateFlags|ntlmNegotiateTargetInfo)
+	copy(msg[24:], []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(msg[44:], uint32(len(msg)))
+	return append(msg, targetInfo...)
+}
+
+func ntlmField(msg []byte, field int) []byte {
+	length := int(binary.LittleEndian.Uint16(msg[field:]))
+	offset := int(binary.LittleEndian.Uint32(msg[field+4:]))
+	return msg[offset : offset+length]
+}
+
+func ntlmAvPair(targetInfo []byte, want uint16) ([]byte, bool) {
+	for av := targetInfo; len(av) >= 4; {
+		id := binary.LittleEndian.Uint16(av)
+		length := int(binary.LittleEndian.Uint16(av[2:]))
+		if id == ntlmAvEOL {
+			break
+		}
+		if id == want {
+			return av[4 : 4+length], true
+		}
+		av = av[4+length:]
+	}
+	return nil, false
+}
+
+func TestNTLMAuthenticateMIC(t *testing.T) {
+	nbDomain := mustHex(t, "02000c00 44006f006d00610069006e00")
+	timestamp := mustHex(t, "07000800 0090d336b734c301")
+	flags := mustHex(t, "06000400 01000000")
+	eol := []byte{0, 0, 0, 0}
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	tests := []struct {
+		name      string
+		target    []byte
+		wantMIC   bool
+		wantFlags uint32
+	}{
+		{"no timestamp", join(nbDomain, eol), false, 0},
+		{"timestamp", join(nbDomain, timestamp, eol), true, ntlmAvFlagMICPresent},
+		{"timestamp and existing flags", join(nbDomain, flags, timestamp, eol), true, 1 | ntlmAvFlagMICPresent},
+	}
+	creds := &proxyCredentials{user: `Domain\User`, pass: "Password"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := ntlmTestChallenge(tt.target)
+			msg, err := ntlmAuthenticate(challenge, creds)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lm := ntlmField(msg, 12)
+			nt := ntlmField(msg, 20)
+			if zero := bytes.Equal(lm, make([]byte, 24)); zero != tt.wantMIC {
+				t.Errorf("LMv2 zeroed = %v, want %v", zero, tt.wantMIC)
+			}
+
+			// Blob: NTProofStr(16) + 28 bytes fixos + target info + 4 zeros
+			blobTarget := nt[16+28 : len(nt)-4]
+			avFlags, ok := ntlmAvPair(blobTarget, ntlmAvFlags)
+			if tt.wantMIC != ok {
+				t.Fatalf("MsvAvFlags present = %v, want %v", ok, tt.wantMIC)
+			}
+			if ok && binary.LittleEndian.Uint32(avFlags) != tt.wantFlags {
+				t.Errorf("MsvAvFlags = %#x, want %#x", binary.LittleEndian.Uint32(avFlags), tt.wantFlags)
+			}
+			if !bytes.HasSuffix(blobTarget, eol) {
+				t.Errorf("target info in response does not end with MsvAvEOL: %x", blobTarget)
+			}
+
+			mic := append([]byte{}, msg[72:88]...)
+			if !tt.wantMIC {
+				if !bytes.Equal(mic, make([]byte, 16)) {
+					t.Errorf("MIC = %x, want zero", mic)
+				}
+				return
+			}
+			sessionKey := hmacMD5(ntowfv2("Password", "User", "Domain"), nt[:16])
+			zeroed := append([]byte{}, msg...)
+			copy(zeroed[72:88], make([]byte, 16))
+			mac := hmac.New(md5.New, sessionKey)
+			mac.Write(ntlmNegotiate())
+			mac.Write(challenge)
+			mac.Write(zeroed)
+			if want := mac.Sum(nil); !bytes.Equal(mic, want) {
+				t.Errorf("MIC = %x, want %x", mic, want)
+			}
+		})
+	}
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	creds := &proxyCredentials{user: "Mufasa", pass: "Circle of Life"}
+	tests := []struct {
+		name    string
+		params  map[string]string
+		newHash func() hash.Hash
+		sess    bool
+		wantErr bool
+	}{
+		{"rfc2069", map[string]string{"realm": "r", "nonce": "n"}, md5.New, false, false},
+		{"md5 qop auth", map[string]string{"realm": "r", "nonce": "n", "qop": "auth,auth-int", "opaque": "o"}, md5.New, false, false},
+		{"sha-256", map[string]string{"realm": "r", "nonce": "n", "qop": "auth", "algorithm": "SHA-256"}, sha256.New, false, false},
+		{"md5-sess", map[string]string{"realm": "r", "nonce": "n", "qop": "auth", "algorithm": "MD5-sess"}, md5.New, true, false},
+		{"unsupported", map[string]string{"realm": "r", "nonce": "n", "algorithm": "SHA-512-256"}, nil, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, err := digestAuthorization(&authChallenge{scheme: "digest", params: tt.params}, creds, "CONNECT", "example.com:443", 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", header)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(header, "Digest ") {
+				t.Fatalf("header = %q", header)
+			}
+			got := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+
+			h := func(s string) string {
+				hh := tt.newHash()
+				hh.Write([]byte(s))
+				return hex.EncodeToString(hh.Sum(nil))
+			}
+			ha1 := h("Mufasa:r:Circle of Life")
+			if tt.sess {
+				ha1 = h(ha1 + ":n:" + got["cnonce"])
+			}
+			ha2 := h("CONNECT:example.com:443")
+			want := h(ha1 + ":n:" + ha2)
+			if tt.params["qop"] != "" {
+				if got["qop"] != "auth" || got["nc"] != "00000001" || got["cnonce"] == "" {
+					t.Fatalf("qop params = %v", got)
+				}
+				want = h(fmt.Sprintf("%s:n:%s:%s:auth:%s", ha1, got["nc"], got["cnonce"], ha2))
+			}
+			if got["response"] != want {
+				t.Errorf("response = %s, want %s", got["response"], want)
+			}
+			if got["opaque"] != tt.params["opaque"] {
+				t.Errorf("opaque = %q, want %q", got["opaque"], tt.params["opaque"])
+			}
+		})
+	}
+}
+
+func TestParseAuthChallenges(t *testing.T) {
+	got := parseAuthChallenges([]string{
+		`Digest realm="proxy, inc", nonce="abc\"d", qop="auth", stale=true`,
+		"NTLM TlRMTVNTUAACAAAA",
+		"Basic realm=x",
+	})
+	if len(got) != 3 {
+		t.Fatalf("got %d challenges", len(got))
+	}
+	tests := []struct {
+		scheme, key, want string
+	}{
+		{"digest", "realm", "proxy, inc"},
+		{"digest", "nonce", `abc"d`},
+		{"digest", "stale", "true"},
+		{"basic", "realm", "x"},
+	}
+	for _, tt := range tests {
+		ch := findChallenge(got, tt.scheme)
+		if ch == nil || ch.params[tt.key] != tt.want {
+			t.Errorf("%s %s = %v, want %q", tt.scheme, tt.key, ch, tt.want)
+		}
+	}
+	if ch := findChallenge(got, "ntlm"); ch == nil || ch.token != "TlRMTVNTUAACAAAA" {
+		t.Errorf("ntlm token = %v", ch)
+	}
+}
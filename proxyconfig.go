@@ -0,0 +1,183 @@
+package browserclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultProxySessionLength = 10
+
+// ProxyProvider descreve como um provedor de proxies residenciais codifica
+// geolocalização e sessão nas credenciais.
+//
+// Os templates aceitam os campos {user}, {pass}, {country}, {COUNTRY},
+// {city}, {state}, {asn} e {session}. Trechos entre colchetes são opcionais e
+// só entram quando todos os campos dentro deles estão preenchidos, ex.:
+// "{user}[-country-{country}][-session-{session}]".
+type ProxyProvider struct {
+	Name             string
+	UsernameTemplate string
+	PasswordTemplate string
+	// Tamanho máximo do ID de sessão aceito pelo provedor
+	SessionLength int
+}
+
+// Templates de provedores conhecidos
+var (
+	ProviderBrightData = ProxyProvider{
+		Name:             "brightdata",
+		UsernameTemplate: "{user}[-country-{country}][-state-{state}][-city-{city}][-asn-{asn}][-session-{session}]",
+		PasswordTemplate: "{pass}",
+	}
+	ProviderOxylabs = ProxyProvider{
+		Name:             "oxylabs",
+		UsernameTemplate: "customer-{user}[-cc-{COUNTRY}][-st-{state}][-city-{city}][-sessid-{session}]",
+		PasswordTemplate: "{pass}",
+	}
+	ProviderSmartproxy = ProxyProvider{
+		Name:             "smartproxy",
+		UsernameTemplate: "user-{user}[-country-{country}][-state-{state}][-city-{city}][-asn-{asn}][-session-{session}]",
+		PasswordTemplate: "{pass}",
+	}
+	ProviderIPRoyal = ProxyProvider{
+		Name:             "iproyal",
+		UsernameTemplate: "{user}",
+		PasswordTemplate: "{pass}[_country-{country}][_state-{state}][_city-{city}][_session-{session}]",
+	}
+)
+
+// ProxyConfig é a forma estruturada de ProxyURL para provedores que usam
+// o usuário/senha para escolher país, cidade, ASN e sessão
+type ProxyConfig struct {
+	Scheme   string // http, https, socks5 (padrão: http)
+	Host     string // host:porta do gateway
+	Username string
+	Password string
+
+	Provider ProxyProvider
+
+	Country string
+	State   string
+	City    string
+	ASN     int
+
+	// Session fixa o ID de sessão. Vazio com StickySession deriva o ID do
+	// SessionID do perfil, mantendo o mesmo IP de saída durante a thread.
+	Session       string
+	StickySession bool
+}
+
+// URL monta a URL do proxy para o perfil
+func (pc *ProxyConfig) URL(profile *BrowserProfile) (string, error) {
+	if pc.Host == "" {
+		return "", errors.New("proxy host is required")
+	}
+
+	scheme := pc.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	fields := map[string]string{
+		"user":    pc.Username,
+		"pass":    pc.Password,
+		"country": strings.ToLower(pc.Country),
+		"COUNTRY": strings.ToUpper(pc.Country),
+		"state":   strings.ToLower(pc.State),
+		"city":    strings.ToLower(strings.ReplaceAll(pc.City, " ", "_")),
+		"session": pc.sessionID(profile),
+	}
+	if pc.ASN > 0 {
+		fields["asn"] = strconv.Itoa(pc.ASN)
+	}
+
+	username, password := pc.Username, pc.Password
+	if pc.Provider.UsernameTemplate != "" {
+		username = expandProxyTemplate(pc.Provider.UsernameTemplate, fields)
+	}
+	if pc.Provider.PasswordTemplate != "" {
+		password = expandProxyTemplate(pc.Provider.PasswordTemplate, fields)
+	}
+
+	u := &url.URL{Scheme: scheme, Host: pc.Host}
+	if username != "" {
+		u.User = url.UserPassword(username, password)
+	}
+	return u.String(), nil
+}
+
+// sessionID retorna a sessão explícita ou uma derivada do perfil
+func (pc *ProxyConfig) sessionID(profile *BrowserProfile) string {
+	if pc.Session != "" || !pc.StickySession || profile == nil {
+		return pc.Session
+	}
+
+	length := pc.Provider.SessionLength
+	if length <= 0 {
+		length = defaultProxySessionLength
+	}
+
+	// Hash do SessionID: estável por perfil e só com caracteres aceitos pelos provedores
+	sum := sha256.Sum256([]byte(profile.SessionID))
+	id := hex.EncodeToString(sum[:])
+	if length < len(id) {
+		id = id[:length]
+	}
+	return id
+}
+
+// expandProxyTemplate substitui os campos e descarta trechos opcionais vazios
+func expandProxyTemplate(tmpl string, fields map[string]string) string {
+	var out strings.Builder
+	for len(tmpl) > 0 {
+		start := strings.IndexByte(tmpl, '[')
+		if start < 0 {
+			out.WriteString(replaceProxyFields(tmpl, fields, nil))
+			break
+		}
+		out.WriteString(replaceProxyFields(tmpl[:start], fields, nil))
+
+		end := strings.IndexByte(tmpl[start:], ']')
+		if end < 0 {
+			out.WriteString(replaceProxyFields(tmpl[start:], fields, nil))
+			break
+		}
+
+		missing := false
+		segment := replaceProxyFields(tmpl[start+1:start+end], fields, &missing)
+		if !missing {
+			out.WriteString(segment)
+		}
+		tmpl = tmpl[start+end+1:]
+	}
+	return out.String()
+}
+
+func replaceProxyFields(s string, fields map[string]string, missing *bool) string {
+	var out strings.Builder
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			out.WriteString(s)
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			out.WriteString(s)
+			break
+		}
+
+		out.WriteString(s[:start])
+		value := fields[s[start+1:start+end]]
+		if value == "" && missing != nil {
+			*missing = true
+		}
+		out.WriteString(value)
+		s = s[start+end+1:]
+	}
+	return out.String()
+}
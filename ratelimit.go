@@ -0,0 +1,341 @@
+package browserclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	defaultSlowdownFactor   = 0.5
+	defaultRecoveryInterval = 30 * time.Second
+	// Aumento da taxa a cada RecoveryInterval sem 429/503
+	rateRecoveryStep = 1.25
+)
+
+// RateLimitKey define o agrupamento dos limites
+type RateLimitKey int
+
+const (
+	// RateLimitByHost limita cada host separadamente
+	RateLimitByHost RateLimitKey = iota
+	// RateLimitByDomain agrupa subdomínios pelo domínio registrável (eTLD+1)
+	RateLimitByDomain
+)
+
+// HostLimit são os limites de um host ou domínio
+type HostLimit struct {
+	// Requisições por segundo (token bucket); 0 desativa
+	RequestsPerSecond float64
+	// Rajada máxima do bucket (padrão: 1)
+	Burst int
+	// Requisições simultâneas; 0 desativa
+	MaxConcurrent int
+}
+
+// RateLimiter aplica token bucket e limite de concorrência por host ou
+// domínio. Pode ser compartilhado entre vários BrowserClient.
+type RateLimiter struct {
+	Key RateLimitKey
+	// Limites padrão e exceções por host/domínio
+	Default   HostLimit
+	Overrides map[string]HostLimit
+
+	// Slowdown adaptativo: 429/503 multiplicam a taxa por SlowdownFactor (até
+	// MinRequestsPerSecond) e ela volta aos poucos após RecoveryInterval
+	Adaptive             bool
+	SlowdownFactor       float64
+	MinRequestsPerSecond float64
+	RecoveryInterval     time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	mu           sync.Mutex
+	limit        HostLimit
+	rate         float64
+	tokens       float64
+	last         time.Time
+	pausedUntil  time.Time
+	lastSlowdown time.Time
+
+	slots chan struct{}
+}
+
+// RateLimitStatus é um retrato do estado de um host
+type RateLimitStatus struct {
+	Key               string
+	RequestsPerSecond float64
+	InFlight          int
+	PausedUntil       time.Time
+}
+
+// NewRateLimiter cria um limitador com os mesmos limites para todos os hosts
+func NewRateLimiter(key RateLimitKey, requestsPerSecond float64, burst, maxConcurrent int) *RateLimiter {
+	return &RateLimiter{
+		Key: key,
+		Default: HostLimit{
+			RequestsPerSecond: requestsPerSecond,
+			Burst:             burst,
+			MaxConcurrent:     maxConcurrent,
+		},
+		hosts: make(map[string]*hostLimiter),
+	}
+}
+
+// keyFor agrupa o host conforme Key
+func (rl *RateLimiter) keyFor(host string) string {
+	host = strings.ToLower(host)
+	if rl.Key == RateLimitByDomain && net.ParseIP(host) == nil {
+		if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+			return domain
+		}
+	}
+	return host
+}
+
+func (rl *RateLimiter) limiterFor(host string) (string, *hostLimiter) {
+	key := rl.keyFor(host)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if hl, ok := rl.hosts[key]; ok {
+		return key, hl
+	}
+
+	limit, ok := rl.Overrides[key]
+	if !ok {
+		limit, ok = rl.Overrides[host]
+	}
+	if !ok {
+		limit = rl.Default
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+
+	hl := &hostLimiter{
+		limit:  limit,
+		rate:   limit.RequestsPerSecond,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+	if limit.MaxConcurrent > 0 {
+		hl.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	if rl.hosts == nil {
+		rl.hosts = make(map[string]*hostLimiter)
+	}
+	rl.hosts[key] = hl
+	return key, hl
+}
+
+// Wait bloqueia até a requisição para host poder sair. release deve ser
+// chamado quando ela terminar para liberar a vaga de concorrência.
+func (rl *RateLimiter) Wait(ctx context.Context, host string) (release func(), err error) {
+	_, hl := rl.limiterFor(host)
+
+	if hl.slots != nil {
+		select {
+		case hl.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if hl.slots != nil {
+				<-hl.slots
+			}
+		})
+	}
+
+	if err := hl.reserve(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// reserve consome um token, esperando na fila do bucket se necessário
+func (hl *hostLimiter) reserve(ctx context.Context) error {
+	hl.mu.Lock()
+	now := time.Now()
+	var wait time.Duration
+
+	if now.Before(hl.pausedUntil) {
+		wait = hl.pausedUntil.Sub(now)
+	}
+
+	if hl.rate > 0 {
+		hl.tokens += now.Sub(hl.last).Seconds() * hl.rate
+		if burst := float64(hl.limit.Burst); hl.tokens > burst {
+			hl.tokens = burst
+		}
+		hl.last = now
+
+		// Reserva: o saldo negativo é a fila de quem já está esperando
+		hl.tokens--
+		if hl.tokens < 0 {
+			if d := time.Duration(-hl.tokens / hl.rate * float64(time.Second)); d > wait {
+				wait = d
+			}
+		}
+	}
+	rate := hl.rate
+	hl.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if rate > 0 {
+			hl.mu.Lock()
+			hl.tokens++
+			hl.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+// Observe ajusta a taxa do host conforme o status da resposta
+func (rl *RateLimiter) Observe(host string, resp *http.Response) {
+	_, hl := rl.limiterFor(host)
+
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	now := time.Now()
+	throttled := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+
+	if throttled {
+		// Retry-After pausa o host inteiro, não só esta requisição
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if until := now.Add(after); until.After(hl.pausedUntil) {
+				hl.pausedUntil = until
+			}
+		}
+	}
+
+	if !rl.Adaptive || hl.limit.RequestsPerSecond <= 0 {
+		return
+	}
+
+	recovery := rl.RecoveryInterval
+	if recovery <= 0 {
+		recovery = defaultRecoveryInterval
+	}
+
+	if throttled {
+		// Uma rajada de 429 conta como um único slowdown
+		if now.Sub(hl.lastSlowdown) < time.Second {
+			return
+		}
+		factor := rl.SlowdownFactor
+		if factor <= 0 || factor >= 1 {
+			factor = defaultSlowdownFactor
+		}
+		minRate := rl.MinRequestsPerSecond
+		if minRate <= 0 {
+			minRate = hl.limit.RequestsPerSecond / 10
+		}
+		hl.rate *= factor
+		if hl.rate < minRate {
+			hl.rate = minRate
+		}
+		hl.lastSlowdown = now
+		return
+	}
+
+	if hl.rate < hl.limit.RequestsPerSecond && now.Sub(hl.lastSlowdown) >= recovery {
+		hl.rate *= rateRecoveryStep
+		if hl.rate > hl.limit.RequestsPerSecond {
+			hl.rate = hl.limit.RequestsPerSecond
+		}
+		hl.lastSlowdown = now.Add(-recovery / 2)
+	}
+}
+
+// Status retorna o estado de todos os hosts já vistos
+func (rl *RateLimiter) Status() []RateLimitStatus {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	status := make([]RateLimitStatus, 0, len(rl.hosts))
+	for key, hl := range rl.hosts {
+		hl.mu.Lock()
+		s := RateLimitStatus{Key: key, RequestsPerSecond: hl.rate, PausedUntil: hl.pausedUntil}
+		hl.mu.Unlock()
+		if hl.slots != nil {
+			s.InFlight = len(hl.slots)
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// rateLimitedTransport aplica o RateLimiter a cada ida ao servidor,
+// incluindo redirects e tentativas de retry
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter *RateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	release, err := t.limiter.Wait(req.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	t.limiter.Observe(host, resp)
+
+	// A vaga de concorrência só é liberada quando o corpo for fechado
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (t *rateLimitedTransport) CloseIdleConnections() {
+	if transport, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.release()
+	}
+	return n, err
+}
+
+func (r *releaseOnClose) Close() error {
+	r.release()
+	return r.ReadCloser.Close()
+}
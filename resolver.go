@@ -0,0 +1,719 @@
+package browserclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultDNSTimeout  = 5 * time.Second
+	defaultCacheTTL    = 5 * time.Minute
+	negativeCacheTTL   = 30 * time.Second
+	dnsMessageMimeType = "application/dns-message"
+)
+
+// Resolver resolve nomes para os dials do cliente (uTLS e HTTP simples).
+// *net.Resolver já satisfaz esta interface.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// TTLResolver é implementado por resolvers que conhecem o TTL das respostas
+type TTLResolver interface {
+	Resolver
+	LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error)
+}
+
+// dnsExchanger envia mensagens DNS em formato wire; usado também para
+// consultar registros HTTPS (ECH) pelo mesmo canal
+type dnsExchanger interface {
+	exchange(ctx context.Context, query []byte) ([]byte, error)
+}
+
+// dnsExchangerFor encontra o canal DNS do resolver configurado (DoH/DoT),
+// atravessando cache e hosts estáticos; sem ele, usa UDP em ECHDNSServer
+func dnsExchangerFor(config *ClientConfig) dnsExchanger {
+	if ex := encryptedDNSExchanger(config); ex != nil {
+		return ex
+	}
+	server := config.ECHDNSServer
+	if server == "" {
+		server = defaultECHDNSServer
+	}
+	return udpExchanger{server: server}
+}
+
+// encryptedDNSExchanger retorna o canal DoH/DoT do resolver configurado, ou
+// nil se não houver
+func encryptedDNSExchanger(config *ClientConfig) dnsExchanger {
+	r := config.Resolver
+	for r != nil {
+		if ex, ok := r.(dnsExchanger); ok {
+			return ex
+		}
+		switch inner := r.(type) {
+		case *CachingResolver:
+			r = inner.Upstream
+		case *StaticResolver:
+			r = inner.Fallback
+		default:
+			r = nil
+		}
+	}
+	return nil
+}
+
+// getResolver retorna o resolver configurado ou o do sistema
+func getResolver(config *ClientConfig) Resolver {
+	if config.Resolver != nil {
+		return config.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// DoHResolver resolve via DNS-over-HTTPS (RFC 8484)
+type DoHResolver struct {
+	// URL do endpoint, ex.: https://cloudflare-dns.com/dns-query
+	URL string
+	// Bootstrap é o ip:porta do servidor DoH, evitando resolver o próprio
+	// endpoint pelo DNS do sistema
+	Bootstrap string
+	// Client opcional; por padrão é criado um com o Bootstrap aplicado
+	Client *http.Client
+
+	once sync.Once
+}
+
+// NewDoHResolver cria um resolver DoH; bootstrap pode ser vazio
+func NewDoHResolver(endpoint, bootstrap string) *DoHResolver {
+	return &DoHResolver{URL: endpoint, Bootstrap: bootstrap}
+}
+
+func (r *DoHResolver) client() *http.Client {
+	r.once.Do(func() {
+		if r.Client != nil {
+			return
+		}
+		dialer := &net.Dialer{Timeout: defaultDNSTimeout}
+		transport := &http.Transport{
+			ForceAttemptHTTP2: true,
+			IdleConnTimeout:   90 * time.Second,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if r.Bootstrap != "" {
+					addr = r.Bootstrap
+				}
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+		r.Client = &http.Client{Transport: transport, Timeout: defaultDNSTimeout}
+	})
+	return r.Client
+}
+
+func (r *DoHResolver) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dnsMessageMimeType)
+	req.Header.Set("Accept", dnsMessageMimeType)
+
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+func (r *DoHResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, _, err := r.LookupIPAddrTTL(ctx, host)
+	return addrs, err
+}
+
+func (r *DoHResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	return lookupIPAddrVia(ctx, r, host)
+}
+
+// DoTResolver resolve via DNS-over-TLS (RFC 7858)
+type DoTResolver struct {
+	// Server é o ip:porta do servidor, normalmente na porta 853
+	Server string
+	// ServerName usado para validar o certificado do servidor
+	ServerName string
+
+	mu   sync.Mutex
+	conn *dotConn
+}
+
+// NewDoTResolver cria um resolver DoT
+func NewDoTResolver(server, serverName string) *DoTResolver {
+	return &DoTResolver{Server: server, ServerName: serverName}
+}
+
+// dotIdleTimeout fecha a conexão DoT depois de um tempo sem consultas
+const dotIdleTimeout = 30 * time.Second
+
+func (r *DoTResolver) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	// Conexões reaproveitadas podem ter sido fechadas pelo servidor sem aviso
+	// (RFC 7858 §3.4): nesse caso reconecta e tenta de novo uma vez
+	for attempt := 0; ; attempt++ {
+		conn, reused, err := r.connection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := conn.exchange(ctx, query)
+		if err == nil || !reused || attempt > 0 || ctx.Err() != nil || !conn.closed() {
+			return resp, err
+		}
+	}
+}
+
+// connection devolve a conexão persistente, abrindo outra se a atual caiu
+func (r *DoTResolver) connection(ctx context.Context) (*dotConn, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn != nil && !r.conn.closed() {
+		return r.conn, true, nil
+	}
+
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: defaultDNSTimeout},
+		Config:    &tls.Config{ServerName: r.ServerName},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", r.Server)
+	if err != nil {
+		return nil, false, fmt.Errorf("DoT dial failed: %w", err)
+	}
+	r.conn = newDoTConn(conn)
+	return r.conn, false, nil
+}
+
+// dotConn multiplexa consultas numa única conexão DoT: as respostas podem
+// chegar fora de ordem e são entregues pelo ID da mensagem
+type dotConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[uint16]chan []byte
+	nextID  uint16
+	err     error
+	done    chan struct{}
+}
+
+func newDoTConn(conn net.Conn) *dotConn {
+	c := &dotConn{
+		conn:    conn,
+		pending: make(map[uint16]chan []byte),
+		nextID:  uint16(rand.Intn(1 << 16)),
+		done:    make(chan struct{}),
+	}
+	conn.SetReadDeadline(time.Now().Add(dotIdleTimeout))
+	go c.readLoop()
+	return c
+}
+
+func (c *dotConn) closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *dotConn) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	if len(query) < 12 {
+		return nil, errors.New("DNS query too short")
+	}
+
+	// Cada consulta em voo recebe um ID único na conexão; o ID original
+	// volta na resposta
+	ch := make(chan []byte, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	if len(c.pending) >= 1<<16 {
+		c.mu.Unlock()
+		return nil, errors.New("too many DoT queries in flight")
+	}
+	id := c.nextID
+	for _, busy := c.pending[id]; busy; _, busy = c.pending[id] {
+		id++
+	}
+	c.nextID = id + 1
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	binary.BigEndian.PutUint16(msg[2:], id)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultDNSTimeout)
+	}
+	c.writeMu.Lock()
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(msg)
+	if err == nil {
+		c.conn.SetReadDeadline(time.Now().Add(dotIdleTimeout))
+	}
+	c.writeMu.Unlock()
+	if err != nil {
+		c.fail(err)
+		return nil, err
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		binary.BigEndian.PutUint16(resp, binary.BigEndian.Uint16(query))
+		if _, ok := dnsResponseMatches(query, resp); !ok {
+			return nil, errors.New("DoT response does not match query")
+		}
+		return resp, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("DoT query to %s timed out", c.conn.RemoteAddr())
+	}
+}
+
+func (c *dotConn) readLoop() {
+	for {
+		var length [2]byte
+		if _, err := io.ReadFull(c.conn, length[:]); err != nil {
+			c.fail(err)
+			return
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(c.conn, resp); err != nil {
+			c.fail(err)
+			return
+		}
+		if len(resp) < 12 {
+			continue
+		}
+
+		c.mu.Lock()
+		ch := c.pending[binary.BigEndian.Uint16(resp)]
+		c.mu.Unlock()
+		if ch != nil {
+			select {
+			case ch <- resp:
+			default:
+			}
+		}
+	}
+}
+
+// fail encerra a conexão e derruba as consultas pendentes; a próxima
+// consulta abre uma conexão nova
+func (c *dotConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = fmt.Errorf("DoT connection closed: %w", err)
+	close(c.done)
+	c.conn.Close()
+}
+
+func (r *DoTResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, _, err := r.LookupIPAddrTTL(ctx, host)
+	return addrs, err
+}
+
+func (r *DoTResolver) LookupIPAddrTTL(ctx context.Context, host string) ([]net.IPAddr, time.Duration, error) {
+	return lookupIPAddrVia(ctx, r, host)
+}
+
+// udpExchanger consulta um servidor DNS clássico via UDP
+type udpExchanger struct {
+	server string
+}
+
+func (u udpExchanger) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: defaultDNSTimeout}
+	conn, err := dialer.DialContext(ctx, "udp", u.server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial DNS server: %w", err)
+	}
+	defer conn.Close()
+
+	setDNSDeadline(ctx, conn)
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Datagramas com outro ID ou outra pergunta são descartados: podem ser
+		// respostas atrasadas ou forjadas
+		truncated, ok := dnsResponseMatches(query, buf[:n])
+		if !ok {
+			continue
+		}
+		if truncated {
+			return u.exchangeTCP(ctx, query)
+		}
+		return buf[:n], nil
+	}
+}
+
+// exchangeTCP repete a consulta via TCP quando a resposta UDP veio truncada
+func (u udpExchanger) exchangeTCP(ctx context.Context, query []byte) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: defaultDNSTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", u.server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial DNS server: %w", err)
+	}
+	defer conn.Close()
+
+	resp, err := exchangeStream(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := dnsResponseMatches(query, resp); !ok {
+		return nil, errors.New("DNS response does not match query")
+	}
+	return resp, nil
+}
+
+// dnsResponseMatches confere se resp responde a query (mesmo ID e mesma
+// pergunta) e informa se a resposta veio truncada (TC=1)
+func dnsResponseMatches(query, resp []byte) (truncated bool, ok bool) {
+	var qp, rp dnsmessage.Parser
+	qh, err := qp.Start(query)
+	if err != nil {
+		return false, false
+	}
+	rh, err := rp.Start(resp)
+	if err != nil || !rh.Response || rh.ID != qh.ID {
+		return false, false
+	}
+	qq, err := qp.Question()
+	if err != nil {
+		return false, false
+	}
+	rq, err := rp.Question()
+	if err != nil {
+		return false, false
+	}
+	if qq.Type != rq.Type || qq.Class != rq.Class || !strings.EqualFold(qq.Name.String(), rq.Name.String()) {
+		return false, false
+	}
+	return rh.Truncated, true
+}
+
+// exchangeStream envia uma consulta DNS com prefixo de tamanho (TCP/DoT)
+func exchangeStream(ctx context.Context, conn net.Conn, query []byte) ([]byte, error) {
+	setDNSDeadline(ctx, conn)
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func setDNSDeadline(ctx context.Context, conn net.Conn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(defaultDNSTimeout))
+	}
+}
+
+// lookupIPAddrVia consulta A e AAAA e retorna o menor TTL
+func lookupIPAddrVia(ctx context.Context, ex dnsExchanger, host string) ([]net.IPAddr, time.Duration, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, 0, nil
+	}
+
+	type result struct {
+		addrs []net.IPAddr
+		ttl   time.Duration
+		err   error
+	}
+
+	// Consultas em paralelo, mantendo IPv6 antes de IPv4 como o resolver do sistema
+	qtypes := []dnsmessage.Type{dnsmessage.TypeAAAA, dnsmessage.TypeA}
+	results := make([]result, len(qtypes))
+	var wg sync.WaitGroup
+	for i, qtype := range qtypes {
+		wg.Add(1)
+		go func(i int, qtype dnsmessage.Type) {
+			defer wg.Done()
+			addrs, ttl, err := queryIPs(ctx, ex, host, qtype)
+			results[i] = result{addrs, ttl, err}
+		}(i, qtype)
+	}
+	wg.Wait()
+
+	var addrs []net.IPAddr
+	var minTTL time.Duration
+	var lastErr error
+	for _, res := range results {
+		if res.err != nil {
+			lastErr = res.err
+			continue
+		}
+		addrs = append(addrs, res.addrs...)
+		if len(res.addrs) > 0 && (minTTL == 0 || res.ttl < minTTL) {
+			minTTL = res.ttl
+		}
+	}
+
+	if len(addrs) == 0 {
+		if lastErr == nil {
+			lastErr = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return nil, 0, lastErr
+	}
+	return addrs, minTTL, nil
+}
+
+func queryIPs(ctx context.Context, ex dnsExchanger, host string, qtype dnsmessage.Type) ([]net.IPAddr, time.Duration, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, 0, err
+	}
+	query, err := buildDNSQuery(name, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := ex.exchange(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, &net.DNSError{Err: header.RCode.String(), Name: host, IsNotFound: header.RCode == dnsmessage.RCodeNameError}
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	var addrs []net.IPAddr
+	var minTTL uint32
+	for {
+		rh, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch rh.Type {
+		case dnsmessage.TypeA:
+			r, err := parser.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, net.IPAddr{IP: net.IP(r.A[:])})
+		case dnsmessage.TypeAAAA:
+			r, err := parser.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, net.IPAddr{IP: net.IP(r.AAAA[:])})
+		default:
+			// CNAMEs já vêm resolvidos pelo servidor recursivo
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if minTTL == 0 || rh.TTL < minTTL {
+			minTTL = rh.TTL
+		}
+	}
+
+	return addrs, time.Duration(minTTL) * time.Second, nil
+}
+
+// StaticResolver responde a partir de um mapa fixo de hosts, delegando os
+// demais ao Fallback (ou falhando se não houver)
+type StaticResolver struct {
+	Hosts    map[string][]net.IP
+	Fallback Resolver
+}
+
+func (r *StaticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ips, ok := r.lookup(normalizeHostKey(host)); ok {
+		addrs := make([]net.IPAddr, len(ips))
+		for i, ip := range ips {
+			addrs[i] = net.IPAddr{IP: ip}
+		}
+		return addrs, nil
+	}
+	if r.Fallback != nil {
+		return r.Fallback.LookupIPAddr(ctx, host)
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// lookup aceita chaves do mapa em qualquer caixa e com ponto final
+func (r *StaticResolver) lookup(key string) ([]net.IP, bool) {
+	if ips, ok := r.Hosts[key]; ok {
+		return ips, true
+	}
+	for host, ips := range r.Hosts {
+		if normalizeHostKey(host) == key {
+			return ips, true
+		}
+	}
+	return nil, false
+}
+
+func normalizeHostKey(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// CachingResolver guarda respostas respeitando o TTL do upstream quando ele
+// implementa TTLResolver, ou DefaultTTL caso contrário (zero usa 5 minutos)
+type CachingResolver struct {
+	Upstream   Resolver
+	DefaultTTL time.Duration
+	// MinTTL e MaxTTL limitam os TTLs recebidos (zero = sem limite)
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// NewCachingResolver cria um cache sobre o resolver informado
+func NewCachingResolver(upstream Resolver) *CachingResolver {
+	return &CachingResolver{Upstream: upstream, DefaultTTL: defaultCacheTTL}
+}
+
+func (r *CachingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	key := strings.ToLower(host)
+
+	r.mu.Lock()
+	if entry, ok := r.entries[key]; ok && time.Now().Before(entry.expires) {
+		r.mu.Unlock()
+		return entry.addrs, entry.err
+	}
+	r.mu.Unlock()
+
+	var addrs []net.IPAddr
+	var ttl time.Duration
+	var err error
+	if tr, ok := r.Upstream.(TTLResolver); ok {
+		addrs, ttl, err = tr.LookupIPAddrTTL(ctx, host)
+	} else {
+		addrs, err = r.Upstream.LookupIPAddr(ctx, host)
+		ttl = r.defaultTTL()
+	}
+
+	if err != nil {
+		// Erros de contexto não devem ser cacheados
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		ttl = negativeCacheTTL
+	} else {
+		ttl = r.clampTTL(ttl)
+	}
+
+	r.mu.Lock()
+	if r.entries == nil {
+		r.entries = make(map[string]*dnsCacheEntry)
+	}
+	r.entries[key] = &dnsCacheEntry{addrs: addrs, err: err, expires: time.Now().Add(ttl)}
+	r.mu.Unlock()
+
+	return addrs, err
+}
+
+func (r *CachingResolver) clampTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		ttl = r.defaultTTL()
+	}
+	if r.MinTTL > 0 && ttl < r.MinTTL {
+		ttl = r.MinTTL
+	}
+	if r.MaxTTL > 0 && ttl > r.MaxTTL {
+		ttl = r.MaxTTL
+	}
+	return ttl
+}
+
+// defaultTTL cobre o CachingResolver montado sem NewCachingResolver
+func (r *CachingResolver) defaultTTL() time.Duration {
+	if r.DefaultTTL <= 0 {
+		return defaultCacheTTL
+	}
+	return r.DefaultTTL
+}
+
+// Flush descarta todas as entradas do cache
+func (r *CachingResolver) Flush() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
@@ -0,0 +1,230 @@
+package browserclient
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func testDNSQuery(t *testing.T, host string, qtype dnsmessage.Type) []byte {
+	t.Helper()
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := buildDNSQuery(name, qtype)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+// testDNSResponse monta uma resposta A para query, com ID, nome e TC ajustáveis
+func testDNSResponse(t *testing.T, query []byte, id uint16, host string, truncated bool, ip [4]byte) []byte {
+	t.Helper()
+	var p dnsmessage.Parser
+	if _, err := p.Start(query); err != nil {
+		t.Fatal(err)
+	}
+	q, err := p.Question()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "" {
+		q.Name = dnsmessage.MustNewName(dnsFQDN(host))
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Truncated: truncated})
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Question(q); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}, dnsmessage.AResource{A: ip}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestDNSResponseMatches(t *testing.T) {
+	query := testDNSQuery(t, "example.com", dnsmessage.TypeA)
+	id := binary.BigEndian.Uint16(query)
+	ip := [4]byte{192, 0, 2, 1}
+
+	tests := []struct {
+		name          string
+		resp          []byte
+		wantOK        bool
+		wantTruncated bool
+	}{
+		{"match", testDNSResponse(t, query, id, "", false, ip), true, false},
+		{"case-insensitive name", testDNSResponse(t, query, id, "EXAMPLE.com", false, ip), true, false},
+		{"truncated", testDNSResponse(t, query, id, "", true, ip), true, true},
+		{"wrong id", testDNSResponse(t, query, id+1, "", false, ip), false, false},
+		{"wrong question", testDNSResponse(t, query, id, "example.org", false, ip), false, false},
+		{"query echoed back", query, false, false},
+		{"garbage", []byte{1, 2, 3}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, ok := dnsResponseMatches(query, tt.resp)
+			if ok != tt.wantOK || truncated != tt.wantTruncated {
+				t.Errorf("dnsResponseMatches() = (%v, %v), want (%v, %v)", truncated, ok, tt.wantTruncated, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUDPExchangerValidatesAndFallsBackToTCP(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		t.Skipf("TCP port unavailable: %v", err)
+	}
+	defer tcp.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		query := buf[:n]
+		id := binary.BigEndian.Uint16(query)
+		// Resposta com outro ID precisa ser ignorada; a verdadeira vem truncada
+		udp.WriteTo(testDNSResponse(t, query, id+1, "", false, [4]byte{203, 0, 113, 9}), addr)
+		udp.WriteTo(testDNSResponse(t, query, id, "", true, [4]byte{}), addr)
+	}()
+	go func() {
+		conn, err := tcp.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		resp := testDNSResponse(t, query, binary.BigEndian.Uint16(query), "", false, [4]byte{192, 0, 2, 7})
+		binary.BigEndian.PutUint16(length[:], uint16(len(resp)))
+		conn.Write(append(length[:], resp...))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	addrs, _, err := queryIPs(ctx, udpExchanger{server: udp.LocalAddr().String()}, "example.com", dnsmessage.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.IPv4(192, 0, 2, 7)) {
+		t.Fatalf("addrs = %v, want [192.0.2.7] from the TCP retry", addrs)
+	}
+}
+
+func TestDoTConnPipelinesOutOfOrder(t *testing.T) {
+	client, server := net.Pipe()
+	conn := newDoTConn(client)
+	defer conn.fail(io.EOF)
+
+	// O servidor só responde depois de receber as duas consultas, em ordem inversa
+	go func() {
+		var queries [][]byte
+		for len(queries) < 2 {
+			var length [2]byte
+			if _, err := io.ReadFull(server, length[:]); err != nil {
+				return
+			}
+			query := make([]byte, binary.BigEndian.Uint16(length[:]))
+			if _, err := io.ReadFull(server, query); err != nil {
+				return
+			}
+			queries = append(queries, query)
+		}
+		for i := len(queries) - 1; i >= 0; i-- {
+			var p dnsmessage.Parser
+			p.Start(queries[i])
+			q, _ := p.Question()
+			last := q.Name.Data[q.Name.Length-2] // "a1." / "a2."
+			resp := testDNSResponse(t, queries[i], binary.BigEndian.Uint16(queries[i]), "", false, [4]byte{192, 0, 2, last - '0'})
+			var length [2]byte
+			binary.BigEndian.PutUint16(length[:], uint16(len(resp)))
+			server.Write(append(length[:], resp...))
+		}
+	}()
+
+	hosts := []string{"a1", "a2"}
+	type result struct {
+		host  string
+		query []byte
+		resp  []byte
+		err   error
+	}
+	results := make(chan result, len(hosts))
+	for _, host := range hosts {
+		// Mesmo ID nas duas consultas: a conexão precisa remapear
+		query := testDNSQuery(t, host, dnsmessage.TypeA)
+		binary.BigEndian.PutUint16(query, 0x1234)
+		go func(host string, query []byte) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			resp, err := conn.exchange(ctx, query)
+			results <- result{host, query, resp, err}
+		}(host, query)
+	}
+
+	for range hosts {
+		res := <-results
+		if res.err != nil {
+			t.Fatalf("%s: %v", res.host, res.err)
+		}
+		if _, ok := dnsResponseMatches(res.query, res.resp); !ok {
+			t.Fatalf("%s: response does not match its query", res.host)
+		}
+		want := res.host[1] - '0'
+		if got := res.resp[len(res.resp)-1]; got != want {
+			t.Errorf("%s: got answer for a%d", res.host, got)
+		}
+	}
+}
+
+func TestDoTConnFailsPendingOnClose(t *testing.T) {
+	client, server := net.Pipe()
+	conn := newDoTConn(client)
+
+	go func() {
+		var length [2]byte
+		io.ReadFull(server, length[:])
+		io.ReadFull(server, make([]byte, binary.BigEndian.Uint16(length[:])))
+		server.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := conn.exchange(ctx, testDNSQuery(t, "example.com", dnsmessage.TypeA)); err == nil {
+		t.Fatal("expected error after server closed the connection")
+	}
+	if !conn.closed() {
+		t.Fatal("connection should be marked closed")
+	}
+}
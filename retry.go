@@ -0,0 +1,332 @@
+package browserclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 1 * time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+	defaultMaxRetryAfter  = 2 * time.Minute
+	// Quanto do corpo de um 403 é lido para procurar páginas de desafio
+	challengeSniffLimit = 256 << 10
+)
+
+// Status repetidos por padrão
+var defaultRetryStatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Trechos de páginas de desafio anti-bot conhecidas
+var challengeMarkers = []string{
+	"challenge-platform",
+	"cf-chl-",
+	"Just a moment...",
+	"_Incapsula_Resource",
+	"captcha-delivery.com",
+	"px-captcha",
+	"/_sec/cp_challenge",
+}
+
+// RetryPolicy define quando e como uma requisição é repetida. Vale para
+// qualquer método; o corpo é reenviado via GetBody.
+type RetryPolicy struct {
+	// Total de tentativas, incluindo a primeira
+	MaxAttempts int
+	// Backoff exponencial: BaseDelay * 2^n, limitado por MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Fração aleatória subtraída do backoff (0 a 1; zero usa o padrão 0.5)
+	Jitter float64
+	// NoJitter desliga o jitter: o backoff fica exatamente BaseDelay * 2^n
+	NoJitter bool
+
+	// Status que disparam nova tentativa (padrão: 429, 502, 503, 504)
+	RetryStatusCodes []int
+	// Repetir 403 quando a resposta é uma página de desafio
+	RetryOnChallenge bool
+	// Detector de desafio; o padrão olha headers e marcadores conhecidos no corpo
+	IsChallenge func(resp *http.Response, body []byte) bool
+
+	// Repetir métodos não idempotentes (POST, PATCH) mesmo sem Idempotency-Key
+	RetryNonIdempotent bool
+
+	// Retry-After acima de MaxRetryAfter encerra as tentativas (padrão: 2min)
+	IgnoreRetryAfter bool
+	MaxRetryAfter    time.Duration
+
+	// ShouldRetry substitui a decisão padrão quando definido
+	ShouldRetry func(attempt int, resp *http.Response, err error) bool
+}
+
+// RetryAttempt é o resultado de uma tentativa
+type RetryAttempt struct {
+	StatusCode int
+	Status     string
+	Err        error
+	// Espera antes da próxima tentativa
+	Delay time.Duration
+}
+
+// RetryError é devolvido quando todas as tentativas falham
+type RetryError struct {
+	Method   string
+	URL      string
+	Attempts []RetryAttempt
+	// Motivo da interrupção antes de esgotar as tentativas (ex.: contexto cancelado)
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s failed after %d attempts", e.Method, e.URL, len(e.Attempts))
+	for i, a := range e.Attempts {
+		if a.Err != nil {
+			fmt.Fprintf(&b, "; attempt %d: %v", i+1, a.Err)
+		} else {
+			fmt.Fprintf(&b, "; attempt %d: %s", i+1, a.Status)
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, "; stopped: %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap retorna o motivo da interrupção ou o erro da última tentativa que
+// falhou com erro
+func (e *RetryError) Unwrap() error {
+	if e.Err != nil {
+		return e.Err
+	}
+	for i := len(e.Attempts) - 1; i >= 0; i-- {
+		if e.Attempts[i].Err != nil {
+			return e.Attempts[i].Err
+		}
+	}
+	return nil
+}
+
+// LastStatusCode retorna o status da última tentativa com resposta
+func (e *RetryError) LastStatusCode() int {
+	for i := len(e.Attempts) - 1; i >= 0; i-- {
+		if e.Attempts[i].StatusCode != 0 {
+			return e.Attempts[i].StatusCode
+		}
+	}
+	return 0
+}
+
+// Middleware transforma a política em um elo da cadeia de Do
+func (p *RetryPolicy) Middleware() Middleware {
+	return func(rc *RequestContext, req *http.Request, next Handler) (*http.Response, error) {
+		return p.do(req, next)
+	}
+}
+
+func (p *RetryPolicy) do(req *http.Request, next Handler) (*http.Response, error) {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryAttempts
+	}
+	ctx := req.Context()
+	canRetry := p.RetryNonIdempotent || isIdempotent(req)
+	retryErr := &RetryError{Method: req.Method, URL: req.URL.String()}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			var err error
+			if attemptReq, err = RewindRequest(req); err != nil {
+				retryErr.Err = err
+				return nil, retryErr
+			}
+		}
+
+		resp, err := next(attemptReq)
+
+		result := RetryAttempt{Err: err}
+		if resp != nil {
+			result.StatusCode = resp.StatusCode
+			result.Status = resp.Status
+		}
+
+		retry := canRetry && ctx.Err() == nil && p.shouldRetry(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		delay, ok := p.delay(attempt, resp)
+		result.Delay = delay
+		retryErr.Attempts = append(retryErr.Attempts, result)
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, challengeSniffLimit))
+			resp.Body.Close()
+		}
+		if !ok || attempt >= maxAttempts {
+			return nil, retryErr
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			retryErr.Err = ctx.Err()
+			return nil, retryErr
+		}
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(attempt int, resp *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(attempt, resp, err)
+	}
+	if err != nil {
+		return true
+	}
+
+	codes := p.RetryStatusCodes
+	if codes == nil {
+		codes = defaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == resp.StatusCode {
+			return true
+		}
+	}
+
+	if resp.StatusCode == http.StatusForbidden && p.RetryOnChallenge {
+		return p.isChallenge(resp)
+	}
+	return false
+}
+
+// isChallenge lê o início do corpo para o detector e o devolve intacto à resposta
+func (p *RetryPolicy) isChallenge(resp *http.Response) bool {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, challengeSniffLimit))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), resp.Body), resp.Body}
+
+	body := raw
+	if resp.Header.Get("Content-Encoding") != "" {
+		sniff := &http.Response{Header: resp.Header, Body: io.NopCloser(bytes.NewReader(raw))}
+		if r, err := getResponseReader(sniff); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil || len(decoded) > 0 {
+				body = decoded
+			}
+			r.Close()
+		}
+	}
+
+	if p.IsChallenge != nil {
+		return p.IsChallenge(resp, body)
+	}
+	return isChallengeResponse(resp, body)
+}
+
+// isChallengeResponse reconhece desafios da Cloudflare, DataDome, Imperva,
+// PerimeterX e Akamai pelos headers ou pelo HTML
+func isChallengeResponse(resp *http.Response, body []byte) bool {
+	if strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge") {
+		return true
+	}
+	if resp.Header.Get("X-Datadome") != "" || resp.Header.Get("X-Iinfo") != "" {
+		return true
+	}
+	for _, marker := range challengeMarkers {
+		if bytes.Contains(body, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay calcula a espera antes da próxima tentativa; false indica que o
+// Retry-After pedido passa do limite e não vale a pena esperar
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil && !p.IgnoreRetryAfter {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			maxAfter := p.MaxRetryAfter
+			if maxAfter <= 0 {
+				maxAfter = defaultMaxRetryAfter
+			}
+			return after, after <= maxAfter
+		}
+	}
+
+	base := p.BaseDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if p.NoJitter {
+		return delay, true
+	}
+	jitter := p.Jitter
+	if jitter <= 0 || jitter > 1 {
+		jitter = 0.5
+	}
+	delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	return delay, true
+}
+
+// parseRetryAfter aceita segundos ou data HTTP
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			seconds = 0
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// isIdempotent segue a RFC 9110; POST/PATCH contam com Idempotency-Key
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// retryPolicyFor retorna a política da requisição ou a do cliente
+func (bc *BrowserClient) retryPolicyFor(opts RequestOptions) *RetryPolicy {
+	if opts.Retry != nil {
+		return opts.Retry
+	}
+	return bc.config.RetryPolicy
+}
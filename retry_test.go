@@ -0,0 +1,205 @@
+package browserclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResponse(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	cfChallenge := http.Header{"Cf-Mitigated": {"challenge"}}
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		resp   *http.Response
+		err    error
+		want   bool
+	}{
+		{"transport error", RetryPolicy{}, nil, errors.New("reset"), true},
+		{"200", RetryPolicy{}, testResponse(200, nil, ""), nil, false},
+		{"429 default", RetryPolicy{}, testResponse(429, nil, ""), nil, true},
+		{"500 not in defaults", RetryPolicy{}, testResponse(500, nil, ""), nil, false},
+		{"503 default", RetryPolicy{}, testResponse(503, nil, ""), nil, true},
+		{"custom codes", RetryPolicy{RetryStatusCodes: []int{500}}, testResponse(500, nil, ""), nil, true},
+		{"custom codes exclude defaults", RetryPolicy{RetryStatusCodes: []int{500}}, testResponse(503, nil, ""), nil, false},
+		{"403 without RetryOnChallenge", RetryPolicy{}, testResponse(403, cfChallenge, ""), nil, false},
+		{"403 challenge header", RetryPolicy{RetryOnChallenge: true}, testResponse(403, cfChallenge, ""), nil, true},
+		{"403 challenge body", RetryPolicy{RetryOnChallenge: true}, testResponse(403, nil, "<title>Just a moment...</title>"), nil, true},
+		{"403 plain", RetryPolicy{RetryOnChallenge: true}, testResponse(403, nil, "forbidden"), nil, false},
+		{
+			"ShouldRetry overrides",
+			RetryPolicy{ShouldRetry: func(_ int, resp *http.Response, err error) bool { return err != nil || resp.StatusCode >= 500 }},
+			testResponse(501, nil, ""), nil, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.shouldRetry(1, tt.resp, tt.err); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyChallengeKeepsBody(t *testing.T) {
+	p := &RetryPolicy{RetryOnChallenge: true}
+	resp := testResponse(403, nil, "<html>cf-chl-bypass</html>")
+	if !p.shouldRetry(1, resp, nil) {
+		t.Fatal("expected challenge to be detected")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "<html>cf-chl-bypass</html>" {
+		t.Errorf("body after sniffing = %q", body)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		resp     *http.Response
+		min, max time.Duration
+		wantOK   bool
+	}{
+		{"first backoff no jitter", RetryPolicy{BaseDelay: time.Second, NoJitter: true}, 1, nil, time.Second, time.Second, true},
+		{"third backoff no jitter", RetryPolicy{BaseDelay: time.Second, NoJitter: true}, 3, nil, 4 * time.Second, 4 * time.Second, true},
+		{"capped by MaxDelay", RetryPolicy{BaseDelay: time.Second, MaxDelay: 3 * time.Second, NoJitter: true}, 5, nil, 3 * time.Second, 3 * time.Second, true},
+		{"default jitter", RetryPolicy{BaseDelay: time.Second}, 1, nil, 500 * time.Millisecond, time.Second, true},
+		{"full jitter", RetryPolicy{BaseDelay: time.Second, Jitter: 1}, 1, nil, 0, time.Second, true},
+		{
+			"Retry-After seconds",
+			RetryPolicy{}, 1, testResponse(429, http.Header{"Retry-After": {"7"}}, ""),
+			7 * time.Second, 7 * time.Second, true,
+		},
+		{
+			"Retry-After above limit",
+			RetryPolicy{MaxRetryAfter: time.Minute}, 1, testResponse(503, http.Header{"Retry-After": {"120"}}, ""),
+			2 * time.Minute, 2 * time.Minute, false,
+		},
+		{
+			"Retry-After ignored",
+			RetryPolicy{BaseDelay: time.Second, NoJitter: true, IgnoreRetryAfter: true}, 2, testResponse(503, http.Header{"Retry-After": {"120"}}, ""),
+			2 * time.Second, 2 * time.Second, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got, ok := tt.policy.delay(tt.attempt, tt.resp)
+				if ok != tt.wantOK || got < tt.min || got > tt.max {
+					t.Fatalf("delay() = (%v, %v), want [%v, %v], %v", got, ok, tt.min, tt.max, tt.wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-5", 0, true},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = (%v, %v), want (%v, %v)", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		header string
+		want   bool
+	}{
+		{http.MethodGet, "", true},
+		{http.MethodPut, "", true},
+		{http.MethodDelete, "", true},
+		{http.MethodPost, "", false},
+		{http.MethodPatch, "", false},
+		{http.MethodPost, "Idempotency-Key", true},
+		{http.MethodPatch, "X-Idempotency-Key", true},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "https://example.com/", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, "abc")
+		}
+		if got := isIdempotent(req); got != tt.want {
+			t.Errorf("isIdempotent(%s, %q) = %v, want %v", tt.method, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		policy       RetryPolicy
+		statuses     []int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"succeeds after 503", http.MethodGet, RetryPolicy{}, []int{503, 200}, 2, 200},
+		{"gives up after MaxAttempts", http.MethodGet, RetryPolicy{MaxAttempts: 2}, []int{503, 503, 503}, 2, 0},
+		{"POST is not retried", http.MethodPost, RetryPolicy{}, []int{503, 200}, 1, 503},
+		{"POST with RetryNonIdempotent", http.MethodPost, RetryPolicy{RetryNonIdempotent: true}, []int{503, 200}, 2, 200},
+		{"non-retryable status returned", http.MethodGet, RetryPolicy{}, []int{404, 200}, 1, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.BaseDelay = time.Millisecond
+			tt.policy.NoJitter = true
+
+			attempts := 0
+			next := func(req *http.Request) (*http.Response, error) {
+				status := tt.statuses[attempts]
+				attempts++
+				return testResponse(status, nil, ""), nil
+			}
+			req, _ := http.NewRequest(tt.method, "https://example.com/", nil)
+			resp, err := tt.policy.do(req, next)
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantStatus == 0 {
+				var retryErr *RetryError
+				if !errors.As(err, &retryErr) || len(retryErr.Attempts) != tt.wantAttempts {
+					t.Fatalf("err = %v, want RetryError with %d attempts", err, tt.wantAttempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package browserclient
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+const defaultSessionCacheSize = 64
+
+// Caches de sessão TLS por perfil (chave: SessionID do perfil)
+var profileSessionCaches sync.Map
+
+// tlsSessionCache é um cache LRU de tickets TLS que pode ser persistido.
+//
+// Em TLS 1.3 os tickets são usados para resumption via PSK; em TLS 1.2, por
+// session ticket (RFC 5077).
+//
+// Fora do escopo, por limitação do uTLS v1.8 (herdada do crypto/tls):
+//   - resumption por session ID em TLS 1.2: o cliente sempre manda um
+//     session ID aleatório e só retoma com ticket;
+//   - early data (0-RTT): o cliente só oferece early_data em QUIC, então
+//     GETs idempotentes também esperam o handshake completo.
+type tlsSessionCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type sessionCacheEntry struct {
+	key   string
+	state *utls.ClientSessionState
+}
+
+// persistedSession é o formato serializado de uma entrada do cache
+type persistedSession struct {
+	Key    string `json:"key"`
+	Ticket []byte `json:"ticket"`
+	State  []byte `json:"state"`
+}
+
+type persistedSessionCache struct {
+	Version  int                `json:"version"`
+	Sessions []persistedSession `json:"sessions"`
+}
+
+func newTLSSessionCache(capacity int) *tlsSessionCache {
+	if capacity <= 0 {
+		capacity = defaultSessionCacheSize
+	}
+	return &tlsSessionCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// getSessionCache retorna o cache de sessões do perfil, criando se necessário
+func getSessionCache(profile *BrowserProfile, config *ClientConfig) *tlsSessionCache {
+	if cache, ok := profileSessionCaches.Load(profile.SessionID); ok {
+		return cache.(*tlsSessionCache)
+	}
+	cache, _ := profileSessionCaches.LoadOrStore(profile.SessionID, newTLSSessionCache(config.SessionCacheSize))
+	return cache.(*tlsSessionCache)
+}
+
+func (c *tlsSessionCache) Get(key string) (*utls.ClientSessionState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return elem.Value.(*sessionCacheEntry).state, true
+	}
+	return nil, false
+}
+
+func (c *tlsSessionCache) Put(key string, cs *utls.ClientSessionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		if cs == nil {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+			return
+		}
+		elem.Value.(*sessionCacheEntry).state = cs
+		c.lru.MoveToFront(elem)
+		return
+	}
+	if cs == nil {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&sessionCacheEntry{key: key, state: cs})
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*sessionCacheEntry).key)
+	}
+}
+
+// has verifica se existe sessão para a chave sem alterar a ordem do LRU
+func (c *tlsSessionCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+// Save serializa o cache em JSON
+func (c *tlsSessionCache) Save(w io.Writer) error {
+	c.mu.Lock()
+	out := persistedSessionCache{Version: 1}
+	for elem := c.lru.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*sessionCacheEntry)
+		ticket, state, err := entry.state.ResumptionState()
+		if err != nil || state == nil {
+			continue
+		}
+		stateBytes, err := state.Bytes()
+		if err != nil {
+			continue
+		}
+		out.Sessions = append(out.Sessions, persistedSession{
+			Key:    entry.key,
+			Ticket: ticket,
+			State:  stateBytes,
+		})
+	}
+	c.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(out)
+}
+
+// Load restaura sessões salvas com Save, mantendo as já existentes
+func (c *tlsSessionCache) Load(r io.Reader) error {
+	var in persistedSessionCache
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return fmt.Errorf("failed to decode session cache: %w", err)
+	}
+	if in.Version != 1 {
+		return fmt.Errorf("unsupported session cache version: %d", in.Version)
+	}
+
+	for _, s := range in.Sessions {
+		state, err := utls.ParseSessionState(s.State)
+		if err != nil {
+			continue
+		}
+		cs, err := utls.NewResumptionState(s.Ticket, state)
+		if err != nil {
+			continue
+		}
+		c.Put(s.Key, cs)
+	}
+	return nil
+}
+
+// resumableSpec retorna o ClientHelloSpec do fingerprint com a extensão
+// pre_shared_key, como o navegador faz ao retomar uma sessão TLS 1.3.
+// Retorna nil quando o fingerprint não possui spec conhecido (ex.: randomizado).
+func resumableSpec(fingerprint utls.ClientHelloID) *utls.ClientHelloSpec {
+	spec, err := utls.UTLSIdToSpec(fingerprint)
+	if err != nil {
+		return nil
+	}
+	for _, ext := range spec.Extensions {
+		if _, ok := ext.(utls.PreSharedKeyExtension); ok {
+			return &spec
+		}
+	}
+	// A extensão PSK deve ser sempre a última do ClientHello
+	spec.Extensions = append(spec.Extensions, &utls.UtlsPreSharedKeyExtension{})
+	return &spec
+}
+
+// SaveTLSSessions persiste os tickets TLS do perfil para reuso em outra execução
+func (bc *BrowserClient) SaveTLSSessions(w io.Writer) error {
+	return getSessionCache(bc.profile, bc.config).Save(w)
+}
+
+// LoadTLSSessions carrega tickets TLS salvos com SaveTLSSessions
+func (bc *BrowserClient) LoadTLSSessions(r io.Reader) error {
+	return getSessionCache(bc.profile, bc.config).Load(r)
+}
@@ -0,0 +1,166 @@
+package browserclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/http2"
+)
+
+// browserTransport envia cada host HTTPS pelo transport HTTP/2 ou HTTP/1.1
+// conforme o ALPN negociado pelo uTLS. O http.Transport padrão só detecta h2
+// em *tls.Conn, então conexões uTLS sempre falariam HTTP/1.1 com ele.
+type browserTransport struct {
+	h1      *http.Transport
+	h2      *http2.Transport
+	dialTLS func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Pool usado nas requisições HTTP simples encaminhadas ao proxy
+	proxies *ProxyPool
+
+	mu        sync.Mutex
+	protocols map[string]string
+	pending   map[string]net.Conn
+}
+
+func newBrowserTransport(h1 *http.Transport, dialTLS func(ctx context.Context, network, addr string) (net.Conn, error)) *browserTransport {
+	t := &browserTransport{
+		h1:        h1,
+		dialTLS:   dialTLS,
+		protocols: make(map[string]string),
+		pending:   make(map[string]net.Conn),
+	}
+
+	h1.DialTLSContext = t.dialPending
+	t.h2 = &http2.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return t.dialPending(ctx, network, addr)
+		},
+		IdleConnTimeout: h1.IdleConnTimeout,
+	}
+	return t
+}
+
+// http2Settings são os SETTINGS do navegador que o x/net/http2 permite
+// ajustar. ENABLE_PUSH, INITIAL_WINDOW_SIZE e o WINDOW_UPDATE inicial são
+// fixos no x/net e continuam com os valores do Go.
+type http2Settings struct {
+	headerTableSize   uint32
+	maxHeaderListSize uint32
+	maxFrameSize      uint32
+}
+
+// noHeaderListSize faz o x/net omitir SETTINGS_MAX_HEADER_LIST_SIZE
+const noHeaderListSize = 0xffffffff
+
+// Por engine: o Chrome do Android e o Safari do iOS mandam os mesmos
+// SETTINGS que as versões desktop. MAX_FRAME_SIZE é sempre enviado pelo
+// x/net, então fica no padrão do protocolo (16384).
+var browserHTTP2Settings = map[string]http2Settings{
+	"Chromium": {headerTableSize: 65536, maxHeaderListSize: 262144, maxFrameSize: 16384},
+	"Firefox":  {headerTableSize: 65536, maxHeaderListSize: noHeaderListSize, maxFrameSize: 16384},
+	"Safari":   {headerTableSize: 4096, maxHeaderListSize: noHeaderListSize, maxFrameSize: 16384},
+}
+
+// applyHTTP2Settings configura o transport h2 com os SETTINGS do perfil
+func applyHTTP2Settings(h2 *http2.Transport, profile *BrowserProfile) {
+	engine := "Chromium"
+	switch browser := profileBrowser(profile); browser {
+	case "Firefox", "Safari":
+		engine = browser
+	}
+	settings := browserHTTP2Settings[engine]
+	h2.MaxDecoderHeaderTableSize = settings.headerTableSize
+	h2.MaxHeaderListSize = settings.maxHeaderListSize
+	h2.MaxReadFrameSize = settings.maxFrameSize
+}
+
+func (t *browserTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// HTTPS via proxy também passa por aqui: o túnel é aberto pelo dialTLS
+	if req.URL.Scheme != "https" {
+		if t.proxies != nil {
+			return t.roundTripForwardProxy(req)
+		}
+		return t.h1.RoundTrip(req)
+	}
+
+	proto, err := t.protocolFor(req.Context(), canonicalAddr(req))
+	if err != nil {
+		return nil, err
+	}
+	if proto == http2.NextProtoTLS {
+		return t.h2.RoundTrip(req)
+	}
+	return t.h1.RoundTrip(req)
+}
+
+// protocolFor retorna o ALPN conhecido do host ou abre a primeira conexão
+// para descobri-lo; a conexão fica reservada para o transport escolhido
+func (t *browserTransport) protocolFor(ctx context.Context, addr string) (string, error) {
+	t.mu.Lock()
+	proto, ok := t.protocols[addr]
+	t.mu.Unlock()
+	if ok {
+		return proto, nil
+	}
+
+	conn, err := t.dialTLS(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	proto = negotiatedProtocol(conn)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocols[addr] = proto
+	if _, exists := t.pending[addr]; exists {
+		conn.Close()
+	} else {
+		t.pending[addr] = conn
+	}
+	return proto, nil
+}
+
+// dialPending entrega a conexão já negociada ou disca uma nova
+func (t *browserTransport) dialPending(ctx context.Context, network, addr string) (net.Conn, error) {
+	t.mu.Lock()
+	conn, ok := t.pending[addr]
+	delete(t.pending, addr)
+	t.mu.Unlock()
+	if ok {
+		return conn, nil
+	}
+	return t.dialTLS(ctx, network, addr)
+}
+
+func (t *browserTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	for addr, conn := range t.pending {
+		conn.Close()
+		delete(t.pending, addr)
+	}
+	t.mu.Unlock()
+
+	t.h1.CloseIdleConnections()
+	t.h2.CloseIdleConnections()
+}
+
+func negotiatedProtocol(conn net.Conn) string {
+	if tc, ok := conn.(*tlsConn); ok {
+		return tc.ConnectionState().NegotiatedProtocol
+	}
+	return ""
+}
+
+func canonicalAddr(req *http.Request) string {
+	port := req.URL.Port()
+	if port == "" {
+		port = "443"
+		if req.URL.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
@@ -0,0 +1,136 @@
+package browserclient
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// PeerCertificateInfo expõe a cadeia apresentada pelo servidor para o
+// callback de verificação customizado
+type PeerCertificateInfo struct {
+	Host             string
+	PeerCertificates []*x509.Certificate
+	// VerifiedChains fica vazio quando DisableTLSVerify está ativo
+	VerifiedChains [][]*x509.Certificate
+}
+
+// PinFailure descreve uma falha de pinning SPKI
+type PinFailure struct {
+	Host             string
+	PeerCertificates []*x509.Certificate
+	ExpectedPins     []string
+	ReportOnly       bool
+}
+
+// loadRootCAs monta o pool de raízes a partir de RootCAs/RootCAFiles, sem
+// alterar o config: os bundles vão para uma cópia de RootCAs ou do pool do
+// sistema. nil mantém o pool do sistema.
+func loadRootCAs(config *ClientConfig) (*x509.CertPool, error) {
+	if len(config.RootCAFiles) == 0 {
+		return config.RootCAs, nil
+	}
+
+	var pool *x509.CertPool
+	if config.RootCAs != nil {
+		pool = config.RootCAs.Clone()
+	} else {
+		pool = getSystemCertPool()
+	}
+
+	for _, file := range config.RootCAFiles {
+		pemData, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root CA bundle %s: %w", file, err)
+		}
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in root CA bundle %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// SPKIPin calcula o pin (sha256/base64 da SubjectPublicKeyInfo) de um certificado
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// pinsForHost retorna os pins do host, aceitando entradas "*.dominio"
+func pinsForHost(pins map[string][]string, host string) []string {
+	if p, ok := pins[host]; ok {
+		return p
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		if p, ok := pins["*"+host[i:]]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func normalizePin(pin string) string {
+	if strings.HasPrefix(pin, "sha256/") {
+		return pin
+	}
+	return "sha256/" + pin
+}
+
+// checkPins verifica se algum certificado da cadeia bate com os pins esperados
+func checkPins(expected []string, state utls.ConnectionState) bool {
+	// Cópia: append não pode escrever no array de PeerCertificates
+	candidates := append([]*x509.Certificate(nil), state.PeerCertificates...)
+	for _, chain := range state.VerifiedChains {
+		candidates = append(candidates, chain...)
+	}
+
+	for _, cert := range candidates {
+		pin := SPKIPin(cert)
+		for _, want := range expected {
+			if normalizePin(want) == pin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildVerifyConnection combina pinning e o callback do usuário numa única
+// função de VerifyConnection; não altera o ClientHello
+func buildVerifyConnection(host string, config *ClientConfig) func(utls.ConnectionState) error {
+	pins := pinsForHost(config.CertificatePins, host)
+	if len(pins) == 0 && config.VerifyPeerCertificate == nil {
+		return nil
+	}
+
+	return func(state utls.ConnectionState) error {
+		if len(pins) > 0 && !checkPins(pins, state) {
+			failure := &PinFailure{
+				Host:             host,
+				PeerCertificates: state.PeerCertificates,
+				ExpectedPins:     pins,
+				ReportOnly:       config.PinReportOnly,
+			}
+			if config.PinFailureHandler != nil {
+				config.PinFailureHandler(failure)
+			}
+			if !config.PinReportOnly {
+				return fmt.Errorf("certificate pin mismatch for %s", host)
+			}
+		}
+
+		if config.VerifyPeerCertificate != nil {
+			return config.VerifyPeerCertificate(&PeerCertificateInfo{
+				Host:             host,
+				PeerCertificates: state.PeerCertificates,
+				VerifiedChains:   state.VerifiedChains,
+			})
+		}
+		return nil
+	}
+}
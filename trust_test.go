@@ -0,0 +1,91 @@
+package browserclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+func TestPinsForHost(t *testing.T) {
+	pins := map[string][]string{
+		"api.example.com":   {"exact"},
+		"*.example.com":     {"wildcard"},
+		"*.cdn.example.net": {"cdn"},
+	}
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"api.example.com", []string{"exact"}},
+		{"www.example.com", []string{"wildcard"}},
+		{"a.cdn.example.net", []string{"cdn"}},
+		{"example.com", nil},
+		{"a.b.example.com", nil},
+		{"cdn.example.net", nil},
+		{"localhost", nil},
+	}
+	for _, tt := range tests {
+		if got := pinsForHost(pins, tt.host); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pinsForHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func testCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pin.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCheckPins(t *testing.T) {
+	leaf, root := testCertificate(t), testCertificate(t)
+	leafPin, rootPin := SPKIPin(leaf), SPKIPin(root)
+
+	tests := []struct {
+		name     string
+		expected []string
+		state    utls.ConnectionState
+		want     bool
+	}{
+		{"leaf pin", []string{leafPin}, utls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, true},
+		{"pin without prefix", []string{strings.TrimPrefix(leafPin, "sha256/")}, utls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, true},
+		{"root from verified chain", []string{rootPin}, utls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{leaf},
+			VerifiedChains:   [][]*x509.Certificate{{leaf, root}},
+		}, true},
+		{"no match", []string{rootPin}, utls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPins(tt.expected, tt.state); got != tt.want {
+				t.Errorf("checkPins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
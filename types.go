@@ -1,39 +1,116 @@
-package browserclient
-
-import (
-	"time"
-)
-
-type ClientConfig struct {
-	ProxyURL        string
-	DisableTLSVerify bool
-	RandomizeTLS    bool
-	ThreadID        int
-	Timeout         time.Duration
-}
-
-type BrowserProfile struct {
-	ViewportWidth  int
-	ViewportHeight int
-	ColorDepth     int
-	PixelRatio     float32
-	Language       string
-	Platform       string
-	Vendor         string
-	TimezoneOffset int
-	SessionID      string
-	CanvasNoise    float32
-	UserAgent      string
-}
-
-type StreamConfig struct {
-	StopOnContent string
-	BufferSize    int
-	MaxBytes      int64
-}
-
-type StreamResult struct {
-	BytesRead    int64
-	Content      string
-	FoundContent bool
+package browserclient
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+type ClientConfig struct {
+	ProxyURL        string
+	DisableTLSVerify bool
+	RandomizeTLS    bool
+	ThreadID        int
+	Timeout         time.Duration
+
+	// Perfil explícito (ex.: GenerateProfile); sem ele, usa o da ThreadID
+	Profile *BrowserProfile
+
+	// Resumption de sessão TLS (tickets por perfil). Sem session ID em TLS
+	// 1.2 e sem 0-RTT: o uTLS não suporta nenhum dos dois no cliente.
+	DisableSessionResumption bool
+	SessionCacheSize         int
+
+	// Encrypted Client Hello: configs estáticas (global ou por host) ou via
+	// registros DNS HTTPS. Sem config, os presets Chrome/Firefox enviam GREASE ECH.
+	// ECHDNSServer só é usado quando o Resolver não é DoH/DoT.
+	ECHConfigList []byte
+	ECHConfigs    map[string][]byte
+	ECHFromDNS    bool
+	ECHDNSServer  string
+
+	// Confiança: RootCAs substitui o pool do sistema e RootCAFiles (PEM) é
+	// somado a ele. CertificatePins mapeia host (ou "*.dominio") para pins
+	// SPKI no formato "sha256/<base64>".
+	RootCAs               *x509.CertPool
+	RootCAFiles           []string
+	CertificatePins       map[string][]string
+	PinReportOnly         bool
+	PinFailureHandler     func(*PinFailure)
+	VerifyPeerCertificate func(*PeerCertificateInfo) error
+
+	// Certificados de cliente (mTLS), escolhidos por host
+	ClientCertificates []ClientCertificate
+
+	// Resolução DNS usada pelos dials uTLS e HTTP simples (padrão: sistema)
+	Resolver Resolver
+
+	// Conexão: família de endereços (Happy Eyeballs por padrão), delay entre
+	// tentativas, timeout do dial e endereço/interface de origem
+	AddressFamily      AddressFamily
+	HappyEyeballsDelay time.Duration
+	DialTimeout        time.Duration
+	LocalAddr          string
+	Interface          string
+
+	// Pool de IPs de origem (tem prioridade sobre LocalAddr/Interface)
+	LocalAddrPool *LocalAddrPool
+
+	// Pool de proxies com rotação e health check (tem prioridade sobre ProxyURL)
+	ProxyPool *ProxyPool
+
+	// Proxy estruturado com geolocalização e sessão do provedor (tem
+	// prioridade sobre ProxyURL)
+	Proxy *ProxyConfig
+
+	// Política de retry padrão de Do (RequestOptions.Retry tem prioridade)
+	RetryPolicy *RetryPolicy
+
+	// Limites de taxa e concorrência por host/domínio (compartilhável)
+	RateLimiter *RateLimiter
+
+	// Pausas humanas entre navegações, com ritmo próprio por perfil
+	Pacing *PacingConfig
+
+	// Cache HTTP privado (RFC 9111) em memória ou disco
+	Cache *HTTPCache
+
+	// Grava todo o tráfego em HAR 1.2 (compartilhável)
+	HAR *HARRecorder
+
+	// Responde a partir de HAR gravado em vez de usar a rede (testes offline)
+	Replay *HARReplayer
+}
+
+type BrowserProfile struct {
+	ViewportWidth  int
+	ViewportHeight int
+	ColorDepth     int
+	PixelRatio     float32
+	Language       string
+	Platform       string
+	Vendor         string
+	TimezoneOffset int
+	SessionID      string
+	CanvasNoise    float32
+	UserAgent      string
+	// Perfil de celular: Sec-Ch-Ua-Mobile ?1
+	Mobile bool
+	// Modelo do aparelho (Sec-Ch-Ua-Model); vazio no desktop e no iOS
+	Model string
+	// Família do navegador (Chrome, Edge, Opera, Brave, Samsung, Firefox, Safari).
+	// Vazio deduz do UserAgent; o Brave usa o mesmo UA do Chrome e só é
+	// reconhecido por aqui.
+	Browser string
+}
+
+type StreamConfig struct {
+	StopOnContent string
+	BufferSize    int
+	MaxBytes      int64
+}
+
+type StreamResult struct {
+	BytesRead    int64
+	Content      string
+	FoundContent bool
 }
\ No newline at end of file